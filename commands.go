@@ -1,61 +1,478 @@
 package main
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
 	sess "tons-of-stats/session"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
 )
 
+// Permissions required for administrative commands.
+var adminPerms int64 = discordgo.PermissionAdministrator
+
 // List of all application commands to register at startup.
 var cmds = []sess.Command{
 	{
 		Definition: &discordgo.ApplicationCommand{
 			Name:        "stats",
-			Description: "Returns your current daily stats, if any have been recorded.",
+			Description: "Returns recorded LoLdle stats.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "me",
+					Description: "Returns your current daily stats, if any have been recorded.",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "user",
+					Description: "Returns another user's current daily stats, if any have been recorded.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionUser,
+							Name:        "user",
+							Description: "User to show the stats for.",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "total",
+					Description: "Returns cumulative stats over all played games.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionUser,
+							Name:        "user",
+							Description: "User to show the stats for. Defaults to yourself.",
+						},
+					},
+				},
+			},
 		},
-		Handler: func(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
-			if i.Member == nil {
-				return nil
-			}
-
-			var msg string
-
-			// Fetch current daily stats for the member invoking the command.
-			if stats, err := dal.Today.Get(i.Member.User.ID); err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					msg = "❌  **No stats recorded.**"
-				} else {
-					log.Warn("Stat retrieval failed", "chID", i.ChannelID, "uID", i.Member.User.ID, "err", err)
-					msg = fmt.Sprintf(
-						"❌  **%s**\n-# %s",
-						"Could not retrieve your stats. Please try again.",
-						"If this error persists, please contact the moderation team.",
-					)
-				}
-			} else {
-				msg = fmt.Sprintf("## %s\n```ansi\n%s\n```", "Daily stats:", stats.String())
-			}
-
-			return &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Flags: sess.IS_COMPONENTS_V2 ^ discordgo.MessageFlagsEphemeral,
-					Components: []discordgo.MessageComponent{
-						discordgo.Container{
-							AccentColor: &ACCENT,
-							Components: []discordgo.MessageComponent{
-								discordgo.TextDisplay{
-									Content: msg,
-								},
-							},
+		Handler: sess.Subcommands(map[string]sess.Handler{
+			"me":    statsMe,
+			"user":  statsUser,
+			"total": statsTotal,
+		}),
+	},
+	{
+		Definition: &discordgo.ApplicationCommand{
+			Name:                     "jobs",
+			Description:              "Manages scheduled jobs.",
+			DefaultMemberPermissions: &adminPerms,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "status",
+					Description: "Returns the status of all scheduled jobs.",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "run",
+					Description: "Immediately runs the missed scheduled runs of a job.",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         "job",
+							Description:  "Name of the job to run.",
+							Required:     true,
+							Autocomplete: true,
 						},
 					},
 				},
-			}
+			},
+		},
+		Handler: sess.Subcommands(map[string]sess.Handler{
+			"status": jobsStatus,
+			"run":    jobsRun,
+		}),
+		Autocomplete: jobsComplete,
+	},
+	{
+		Definition: &discordgo.ApplicationCommand{
+			Name:                     "recalculate",
+			Description:              "Recalculates all ratings using the current scoring rules.",
+			DefaultMemberPermissions: &adminPerms,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "dry_run",
+					Description: "Only report the changes without applying them.",
+				},
+			},
+		},
+		Handler: recalculateCmd,
+	},
+	{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "season",
+			Description: "Returns the current season, past champions and your placements.",
+		},
+		Handler: seasonCmd,
+	},
+	{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "records",
+			Description: "Returns the current server records and your personal bests.",
 		},
+		Handler: recordsCmd,
 	},
 }
+
+// statsOptions contains the options accepted by "/stats" subcommands.
+type statsOptions struct {
+	User string `option:"user"`
+}
+
+// statsMe handles "/stats me", showing the invoking member's daily stats.
+func statsMe(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	if i.Member == nil {
+		return nil
+	}
+
+	return ephemeral(dailyStatsMsg(i, i.Member.User.ID))
+}
+
+// statsUser handles "/stats user", showing the given user's daily stats.
+func statsUser(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	var opts statsOptions
+	if err := sess.DecodeOptions(i, &opts); err != nil || opts.User == "" {
+		log.Warn("Invalid options", "chID", i.ChannelID, "err", err)
+		return nil
+	}
+
+	return ephemeral(dailyStatsMsg(i, opts.User))
+}
+
+// statsTotal handles "/stats total", showing the cumulative stats of either the
+// given user or the invoking member.
+func statsTotal(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	if i.Member == nil {
+		return nil
+	}
+
+	opts := statsOptions{User: i.Member.User.ID}
+	if err := sess.DecodeOptions(i, &opts); err != nil {
+		log.Warn("Invalid options", "chID", i.ChannelID, "err", err)
+		return nil
+	}
+
+	var msg string
+	if stats, err := dal.Total.Get(opts.User); err != nil {
+		msg = errorMsg(i, opts.User, err)
+	} else {
+		rating := fmt.Sprintf("Tier       %s\n", env.Tiers.Of(stats.Elo).Name)
+		if env.RatingMode.ShowGlicko() {
+			rating += fmt.Sprintf("Rating     %s\n", fmtGlicko(stats.Rating, stats.RatingDeviation))
+		}
+		msg = fmt.Sprintf("## %s\n```ansi\n%s%s%s\n```", "Total stats:", stats.String(), rating, fmtDecay(stats))
+	}
+
+	return ephemeral(msg)
+}
+
+// jobsOptions contains the options accepted by "/jobs" subcommands.
+type jobsOptions struct {
+	Job string `option:"job"`
+}
+
+// jobsStatus handles "/jobs status", showing the status of all jobs.
+func jobsStatus(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	status := scheduler.Status()
+
+	msg := "## Jobs\n"
+	for _, st := range status {
+		msg += fmtJobStatus(st)
+	}
+	if len(status) == 0 {
+		msg += "No jobs registered."
+	}
+
+	return ephemeral(msg)
+}
+
+// jobsRun handles "/jobs run", triggering the given job.
+func jobsRun(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	var opts jobsOptions
+	if err := sess.DecodeOptions(i, &opts); err != nil {
+		log.Warn("Invalid options", "chID", i.ChannelID, "err", err)
+		return nil
+	}
+
+	if err := scheduler.Trigger(opts.Job); err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownJob):
+			return ephemeral(fmt.Sprintf("❌  **Unknown job `%s`.**", opts.Job))
+		case errors.Is(err, ErrJobRunning):
+			return ephemeral(fmt.Sprintf("⏳  **Job `%s` is already running.**", opts.Job))
+		case errors.Is(err, ErrJobNotDue):
+			return ephemeral(fmt.Sprintf("⏭️  **Job `%s` is not due.**\n-# %s", opts.Job, "Only missed scheduled runs can be run manually."))
+		default:
+			log.Warn("Job trigger failed", "job", opts.Job, "err", err)
+			return ephemeral(fmt.Sprintf("❌  **Could not run job `%s`.**", opts.Job))
+		}
+	}
+
+	return ephemeral(fmt.Sprintf("✅  **Job `%s` started.**\n-# %s", opts.Job, "Use `/jobs status` to follow its progress."))
+}
+
+// jobsComplete autocompletes job names for "/jobs run".
+func jobsComplete(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	var prefix string
+	if o := sess.Focused(i); o != nil {
+		prefix, _ = o.Value.(string)
+	}
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, name := range scheduler.Names() {
+		if strings.HasPrefix(name, prefix) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+		}
+	}
+
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}
+}
+
+// recalculateOptions contains the options accepted by "/recalculate".
+type recalculateOptions struct {
+	DryRun bool `option:"dry_run"`
+}
+
+// recalculateCmd handles "/recalculate", rescoring all stored submissions and
+// reporting the resulting rating changes.
+//
+// Recalculating may exceed Discord's deadline for responding to interactions,
+// such that the response is deferred and the report sent as a follow-up.
+func recalculateCmd(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	var opts recalculateOptions
+	if err := sess.DecodeOptions(i, &opts); err != nil {
+		log.Warn("Invalid options", "chID", i.ChannelID, "err", err)
+		return nil
+	}
+
+	if err := session.InteractionRespond(i, deferred()); err != nil {
+		return nil
+	}
+
+	changes, err := recalculate(opts.DryRun)
+	if err != nil {
+		followup(i, fmt.Sprintf(
+			"❌  **%s**\n-# %s",
+			"Could not recalculate ratings. No changes were made.",
+			"See the logs for details.",
+		))
+		return nil
+	}
+
+	// Only names of users listed in the report are resolved.
+	listed := changes[:min(len(changes), reportSize)]
+	ids := make([]string, len(listed))
+	for i, c := range listed {
+		ids[i] = c.UserID
+	}
+
+	title := "## Ratings recalculated"
+	if opts.DryRun {
+		title = "## Ratings recalculated (dry run)"
+	} else if leaderboard != nil {
+		// The leaderboard is only set up once the session has been opened.
+		leaderboard.MarkDirty()
+	}
+
+	followup(i, fmt.Sprintf("%s\n%s", title, fmtRatingChanges(changes, session.GetUserNames(ids))))
+	return nil
+}
+
+// Maximum number of past seasons listed by "/season".
+const seasonsShown = 10
+
+// seasonCmd handles "/season", showing past champions and the invoking
+// member's placement in past seasons.
+func seasonCmd(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	if i.Member == nil {
+		return nil
+	}
+	if env.SeasonLength == SeasonNone {
+		return ephemeral("❌  **Seasons are not enabled.**")
+	}
+
+	season, _, err := currentSeason(dal.Seasons)
+	if err != nil {
+		return ephemeral(errorMsg(i, i.Member.User.ID, err))
+	}
+
+	champions, err := dal.SeasonResults.Find(db.Where("rank", "=", 1), db.OrderBy("season", db.Desc), db.Limit(seasonsShown))
+	if err != nil {
+		return ephemeral(errorMsg(i, i.Member.User.ID, err))
+	}
+
+	placements, err := dal.SeasonResults.Find(
+		db.Where("id", "=", i.Member.User.ID),
+		db.OrderBy("season", db.Desc),
+		db.Limit(seasonsShown),
+	)
+	if err != nil {
+		return ephemeral(errorMsg(i, i.Member.User.ID, err))
+	}
+
+	ids := make([]string, len(champions))
+	for i, c := range champions {
+		ids[i] = c.UserID
+	}
+	names := session.GetUserNames(ids)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Season %d\n-# Ends <t:%d:f>\n", season, seasonEnd(puzzleDay(time.Now())).Unix())
+
+	sb.WriteString("### Hall of Fame\n")
+	for _, c := range champions {
+		fmt.Fprintf(&sb, "**Season %d**  ·  🏆 %s (%d Elo)\n", c.Season, names[c.UserID], c.Elo)
+	}
+	if len(champions) == 0 {
+		sb.WriteString("No seasons completed yet.\n")
+	}
+
+	sb.WriteString("### Your placements\n")
+	for _, p := range placements {
+		fmt.Fprintf(&sb, "**Season %d**  ·  #%d (%d Elo, %d days played)\n", p.Season, p.Rank, p.Elo, p.DaysPlayed)
+	}
+	if len(placements) == 0 {
+		sb.WriteString("You have not placed in any season yet.\n")
+	}
+
+	return ephemeral(sb.String())
+}
+
+// recordsCmd handles "/records", showing the holders of all server-wide
+// records as well as the invoking member's personal bests.
+func recordsCmd(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+	if i.Member == nil {
+		return nil
+	}
+
+	records, err := dal.Records.GetAll()
+	if err != nil {
+		return ephemeral(errorMsg(i, i.Member.User.ID, err))
+	}
+
+	bests, err := dal.PersonalBests.Find(db.Where("id", "=", i.Member.User.ID))
+	if err != nil {
+		return ephemeral(errorMsg(i, i.Member.User.ID, err))
+	}
+
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = r.UserID
+	}
+	names := session.GetUserNames(ids)
+
+	byKind := make(map[string]*models.Record, len(records))
+	for _, r := range records {
+		byKind[r.Kind] = r
+	}
+	bestByKind := make(map[string]*models.PersonalBest, len(bests))
+	for _, b := range bests {
+		bestByKind[b.Kind] = b
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Server records\n")
+	for _, k := range recordKinds {
+		if r, ok := byKind[k.Kind]; ok {
+			fmt.Fprintf(&sb, "**%s**  ·  %s by %s (%s)\n", k.Label, fmtRecordValue(r.Kind, r.Value), names[r.UserID], r.Date)
+		} else {
+			fmt.Fprintf(&sb, "**%s**  ·  not set yet\n", k.Label)
+		}
+	}
+
+	sb.WriteString("### Your personal bests\n")
+	for _, k := range recordKinds {
+		if b, ok := bestByKind[k.Kind]; ok {
+			fmt.Fprintf(&sb, "**%s**  ·  %s (%s)\n", k.Label, fmtRecordValue(b.Kind, b.Value), b.Date)
+		}
+	}
+	if len(bests) == 0 {
+		sb.WriteString("No personal bests recorded yet.\n")
+	}
+
+	return ephemeral(sb.String())
+}
+
+// dailyStatsMsg formats the current daily stats for the user with the given
+// ID.
+func dailyStatsMsg(i *discordgo.Interaction, uID string) string {
+	stats, err := dal.Today.Get(uID)
+	if err != nil {
+		return errorMsg(i, uID, err)
+	}
+
+	return fmt.Sprintf("## %s\n```ansi\n%s\n```", "Daily stats:", stats.String())
+}
+
+// errorMsg formats an error that occurred while retrieving stats for the user
+// with the given ID.
+func errorMsg(i *discordgo.Interaction, uID string, err error) string {
+	if errors.Is(err, db.ErrNotFound) {
+		return "❌  **No stats recorded.**"
+	}
+
+	log.Warn("Stat retrieval failed", "chID", i.ChannelID, "uID", uID, "err", err)
+	return fmt.Sprintf(
+		"❌  **%s**\n-# %s",
+		"Could not retrieve stats. Please try again.",
+		"If this error persists, please contact the moderation team.",
+	)
+}
+
+// deferred creates a response acknowledging an interaction, whose actual
+// response only visible to the invoking user follows later (see [followup]).
+func deferred() *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}
+}
+
+// followup completes a deferred response (see [deferred]) to the given
+// interaction, displaying the given message like [ephemeral].
+func followup(i *discordgo.Interaction, msg string) {
+	data := ephemeral(msg).Data
+	if _, err := session.InteractionFollowup(i, &discordgo.WebhookParams{
+		Flags:      data.Flags,
+		Components: data.Components,
+	}); err != nil {
+		log.Warn("Failed to complete deferred response", "chID", i.ChannelID, "err", err)
+	}
+}
+
+// ephemeral creates a response only visible to the invoking user, displaying
+// the given message.
+func ephemeral(msg string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: sess.IS_COMPONENTS_V2 ^ discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.Container{
+					AccentColor: &ACCENT,
+					Components: []discordgo.MessageComponent{
+						discordgo.TextDisplay{
+							Content: msg,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -6,9 +6,36 @@ import (
 )
 
 type DAL struct {
-	DB    *db.DB
-	Today *db.Repository[*models.DailyStats]
-	Total *db.Repository[*models.TotalStats]
+	DB      *db.DB
+	Today   *db.Repository[*models.DailyStats]
+	Total   *db.Repository[*models.TotalStats]
+	History *db.Repository[*models.HistoryStats]
+
+	// Standings is a read-only view over Total, joined with the Elo change from
+	// Today.
+	Standings *db.Repository[*models.Standing]
+
+	// Jobs contains the last successful run of each scheduled job.
+	Jobs *db.Repository[*models.JobRun]
+
+	// Cursors contains the last processed message of each channel.
+	Cursors *db.Repository[*models.Cursor]
+
+	// RatingPeriods contains all puzzle days applied to ratings.
+	RatingPeriods *db.Repository[*models.RatingPeriod]
+
+	// Decay contains all Elo changes caused by inactivity.
+	Decay *db.Repository[*models.Decay]
+
+	// Seasons contains all completed seasons, with the final standings of each
+	// in SeasonResults.
+	Seasons       *db.Repository[*models.Season]
+	SeasonResults *db.Repository[*models.SeasonResult]
+
+	// Records contains the server-wide record of each kind, PersonalBests each
+	// user's best values.
+	Records       *db.Repository[*models.Record]
+	PersonalBests *db.Repository[*models.PersonalBest]
 }
 
 // NewDAL returns a new DAL, initializing all repositories (see [Repository])
@@ -18,5 +45,15 @@ func NewDAL(d *db.DB) *DAL {
 		d,
 		db.NewRepository[*models.DailyStats](d.Conn, "today"),
 		db.NewRepository[*models.TotalStats](d.Conn, "total"),
+		db.NewRepository[*models.HistoryStats](d.Conn, "history"),
+		db.NewRepository[*models.Standing](d.Conn, "standings"),
+		db.NewRepository[*models.JobRun](d.Conn, "jobs"),
+		db.NewRepository[*models.Cursor](d.Conn, "cursors"),
+		db.NewRepository[*models.RatingPeriod](d.Conn, "rating_periods"),
+		db.NewRepository[*models.Decay](d.Conn, "decay"),
+		db.NewRepository[*models.Season](d.Conn, "seasons"),
+		db.NewRepository[*models.SeasonResult](d.Conn, "season_results"),
+		db.NewRepository[*models.Record](d.Conn, "records"),
+		db.NewRepository[*models.PersonalBest](d.Conn, "personal_bests"),
 	}
 }
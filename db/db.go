@@ -1,18 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
-	"strings"
 
 	"github.com/charmbracelet/log"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schema.sql
-var schema string
-
 // Tx represents a transaction used to access a database.
 //
 // A transaction may be an actual transaction, or simply a plain database
@@ -55,15 +51,11 @@ func (db *DB) Close() {
 	db.Conn.Close()
 }
 
-// init configures and bootstraps the underlying database.
+// init configures and bootstraps the underlying database, migrating its schema
+// to the latest version (see [DB.migrate]).
 func (db *DB) init() error {
-	log.Info("Executing database schema")
-	if _, err := db.Conn.Exec(schema); err != nil {
-		log.Error(
-			"Failed to execute database schema",
-			"stmt", strings.ReplaceAll(schema, "\t", "  "),
-			"err", err,
-		)
+	if err := db.migrate(); err != nil {
+		log.Error("Failed to migrate database schema", "err", err)
 		return err
 	}
 
@@ -73,10 +65,11 @@ func (db *DB) init() error {
 // Transaction wraps and executes fn inside of a database transaction. The
 // executed function receives the transaction handle as an argument. If it
 // returns an error, the transaction is rolled back and the error propagated.
-func (db *DB) Transaction(fn func(tx Tx) error) error {
+// The transaction is also rolled back if ctx is done before it is committed.
+func (db *DB) Transaction(ctx context.Context, fn func(tx Tx) error) error {
 	log.Debug("Transaction start", "fn", fn)
 
-	tx, err := db.Conn.Begin()
+	tx, err := db.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		log.Debug("Transaction start failure", "fn", fn, "err", err)
 		return err
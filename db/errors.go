@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrNotFound is returned when a requested entity does not exist.
+	ErrNotFound = errors.New("db: entity not found")
+
+	// ErrDuplicate is returned when creating an entity conflicts with an existing
+	// one (i.e. on primary key or uniqueness violations).
+	ErrDuplicate = errors.New("db: duplicate entity")
+)
+
+// mapErr translates errors returned by the database driver into the matching
+// sentinel errors, if any. The original error is wrapped alongside the
+// sentinel, such that it remains available through [errors.Is] and
+// [errors.As].
+func mapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintUnique:
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		}
+	}
+
+	return err
+}
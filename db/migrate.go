@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Embedded migration files. Each file is named "<version>_<description>.sql",
+// where version is a positive integer. Migrations are applied in ascending
+// order of their versions.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migration is a single, versioned schema change.
+type migration struct {
+	Version int
+	Name    string
+	Stmt    string
+}
+
+// loadMigrations reads and orders all embedded migrations.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration name `%s`", e.Name())
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version `%s`", e.Name())
+		}
+
+		stmt, err := fs.ReadFile(migrationFS, "migrations/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{version, e.Name(), string(stmt)})
+	}
+
+	slices.SortFunc(migrations, func(a, b migration) int { return a.Version - b.Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// version returns the schema version currently applied to the database. A
+// version of 0 indicates that no migrations have been applied yet.
+func (db *DB) version() (int, error) {
+	var v int
+	row := db.Conn.QueryRow("select coalesce(max(version), 0) from schema_version")
+	if err := row.Scan(&v); err != nil {
+		return 0, err
+	}
+
+	return v, nil
+}
+
+// migrate brings the database schema up to date by applying all migrations
+// newer than the current schema version. Each migration is applied inside of
+// its own transaction, together with the corresponding version bump.
+//
+// Databases with a schema version newer than the latest known migration were
+// written by a newer version of the application and are rejected.
+func (db *DB) migrate() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Conn.Exec(
+		"create table if not exists schema_version (version INT NOT NULL PRIMARY KEY)",
+	); err != nil {
+		return err
+	}
+
+	current, err := db.version()
+	if err != nil {
+		return err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+
+	log.Info("Checking database schema", "version", current, "latest", latest)
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		log.Info("Applying migration", "version", m.Version, "name", m.Name)
+		err := db.Transaction(context.Background(), func(tx Tx) error {
+			if _, err := tx.Exec(m.Stmt); err != nil {
+				log.Error(
+					"Failed to apply migration",
+					"name", m.Name,
+					"stmt", strings.ReplaceAll(m.Stmt, "\t", "  "),
+					"err", err,
+				)
+				return err
+			}
+
+			_, err := tx.Exec("insert into schema_version (version) values (?)", m.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration `%s` failed: %v", m.Name, err)
+		}
+	}
+
+	return nil
+}
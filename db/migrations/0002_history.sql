@@ -0,0 +1,16 @@
+-- Table for all past daily stats. Entries are keyed by user and puzzle day.
+CREATE TABLE
+  IF NOT EXISTS
+  history (
+    id            STRING NOT NULL,
+    date          STRING NOT NULL,
+    classic       INT,
+    quote         INT,
+    ability       INT,
+    ability_check BOOL,
+    emoji         INT,
+    splash        INT,
+    splash_check  BOOL,
+    elo_change    INT,
+    PRIMARY KEY (id, date)
+  );
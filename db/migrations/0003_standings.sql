@@ -0,0 +1,12 @@
+-- View joining cumulative stats with the current day's Elo change. Users who
+-- have not played today have an Elo change of 0.
+CREATE VIEW
+  IF NOT EXISTS
+  standings AS
+  SELECT
+    total.id,
+    total.days_played,
+    total.elo,
+    coalesce(today.elo_change, 0) AS elo_change
+  FROM total
+  LEFT JOIN today ON today.id = total.id;
@@ -0,0 +1,8 @@
+-- Table for the last successful run of each scheduled job. Runs are recorded
+-- by the time they were scheduled for, rather than the time they completed.
+CREATE TABLE
+  IF NOT EXISTS
+  jobs (
+    id       STRING NOT NULL PRIMARY KEY,
+    last_run DATETIME NOT NULL
+  );
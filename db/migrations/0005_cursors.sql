@@ -0,0 +1,8 @@
+-- Table for the last processed message of each channel, used to pick up
+-- messages posted while the application was offline.
+CREATE TABLE
+  IF NOT EXISTS
+  cursors (
+    id     STRING NOT NULL PRIMARY KEY,
+    msg_id STRING NOT NULL
+  );
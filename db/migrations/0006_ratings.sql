@@ -0,0 +1,26 @@
+-- Glicko-2 ratings, updated once per puzzle day from the day's history.
+ALTER TABLE total ADD COLUMN rating           REAL NOT NULL DEFAULT 1500;
+ALTER TABLE total ADD COLUMN rating_deviation REAL NOT NULL DEFAULT 350;
+ALTER TABLE total ADD COLUMN volatility       REAL NOT NULL DEFAULT 0.06;
+
+-- Table for puzzle days whose results have been applied to ratings.
+CREATE TABLE
+  IF NOT EXISTS
+  rating_periods (
+    id      STRING NOT NULL PRIMARY KEY,
+    players INT    NOT NULL
+  );
+
+-- Standings additionally include ratings.
+DROP VIEW IF EXISTS standings;
+CREATE VIEW
+  standings AS
+  SELECT
+    total.id,
+    total.days_played,
+    total.elo,
+    coalesce(today.elo_change, 0) AS elo_change,
+    total.rating,
+    total.rating_deviation
+  FROM total
+  LEFT JOIN today ON today.id = total.id;
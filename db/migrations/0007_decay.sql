@@ -0,0 +1,16 @@
+-- Last puzzle day each user submitted results for, used to determine
+-- inactivity. Initialized from history.
+ALTER TABLE total ADD COLUMN last_played STRING NOT NULL DEFAULT '';
+UPDATE total
+  SET last_played = coalesce((SELECT max(date) FROM history WHERE history.id = total.id), '');
+
+-- Table for Elo changes caused by inactivity. Entries are keyed by user and the
+-- puzzle day the decay was applied for.
+CREATE TABLE
+  IF NOT EXISTS
+  decay (
+    id         STRING NOT NULL,
+    date       STRING NOT NULL,
+    elo_change INT    NOT NULL,
+    PRIMARY KEY (id, date)
+  );
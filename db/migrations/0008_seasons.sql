@@ -0,0 +1,21 @@
+-- Table for all completed seasons, numbered starting at 1. Seasons are
+-- identified by the last puzzle day they include.
+CREATE TABLE
+  IF NOT EXISTS
+  seasons (
+    id       INT    NOT NULL PRIMARY KEY,
+    last_day STRING NOT NULL UNIQUE
+  );
+
+-- Table for the final standings of each completed season. Entries are keyed by
+-- user and season.
+CREATE TABLE
+  IF NOT EXISTS
+  season_results (
+    id          STRING NOT NULL,
+    season      INT    NOT NULL,
+    rank        INT    NOT NULL,
+    elo         INT    NOT NULL,
+    days_played INT    NOT NULL,
+    PRIMARY KEY (id, season)
+  );
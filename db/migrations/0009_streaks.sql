@@ -0,0 +1,49 @@
+-- Current and longest streaks of consecutive puzzle days played. Initialized
+-- from history, with current streaks ending on the last day played. Streaks
+-- not extended on the following day are broken by the daily reset.
+ALTER TABLE total ADD COLUMN streak         INT NOT NULL DEFAULT 0;
+ALTER TABLE total ADD COLUMN longest_streak INT NOT NULL DEFAULT 0;
+
+-- Consecutive days share the same difference between day and row number.
+UPDATE total
+  SET
+    streak = coalesce((
+      SELECT count(*)
+      FROM (
+        SELECT
+          date,
+          julianday(date) - row_number() OVER (ORDER BY date) AS run
+        FROM history
+        WHERE history.id = total.id
+      ) AS days
+      GROUP BY run
+      ORDER BY max(date) DESC
+      LIMIT 1
+    ), 0),
+    longest_streak = coalesce((
+      SELECT max(length)
+      FROM (
+        SELECT count(*) AS length
+        FROM (
+          SELECT julianday(date) - row_number() OVER (ORDER BY date) AS run
+          FROM history
+          WHERE history.id = total.id
+        ) AS days
+        GROUP BY run
+      ) AS runs
+    ), 0);
+
+-- Standings additionally include current streaks.
+DROP VIEW IF EXISTS standings;
+CREATE VIEW
+  standings AS
+  SELECT
+    total.id,
+    total.days_played,
+    total.elo,
+    coalesce(today.elo_change, 0) AS elo_change,
+    total.rating,
+    total.rating_deviation,
+    total.streak
+  FROM total
+  LEFT JOIN today ON today.id = total.id;
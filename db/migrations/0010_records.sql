@@ -0,0 +1,20 @@
+-- Table for server-wide records, keyed by kind (see models.Record).
+CREATE TABLE
+  IF NOT EXISTS
+  records (
+    id      STRING NOT NULL PRIMARY KEY,
+    user_id STRING NOT NULL,
+    value   INT    NOT NULL,
+    date    STRING NOT NULL
+  );
+
+-- Table for personal bests. Entries are keyed by user and kind.
+CREATE TABLE
+  IF NOT EXISTS
+  personal_bests (
+    id    STRING NOT NULL,
+    kind  STRING NOT NULL,
+    value INT    NOT NULL,
+    date  STRING NOT NULL,
+    PRIMARY KEY (id, kind)
+  );
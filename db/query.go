@@ -0,0 +1,130 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Order denotes the direction of an ORDER BY-clause (see [OrderBy]).
+type Order string
+
+const (
+	Asc  Order = "asc"
+	Desc Order = "desc"
+)
+
+// Comparison operators allowed in WHERE-clauses (see [Where]).
+var operators = []string{"=", "!=", "<", "<=", ">", ">=", "like"}
+
+// Clause modifies a query built by [Repository.Find]. Clauses are validated
+// against the repository's columns once the query is built.
+type Clause func(q *query)
+
+// query collects all clauses of a single query.
+type query struct {
+	where  []condition
+	order  []ordering
+	limit  int
+	offset int
+}
+
+type condition struct {
+	Column string
+	Op     string
+	Value  any
+}
+
+type ordering struct {
+	Column string
+	Order  Order
+}
+
+// Where filters results to rows for which the given column compares to value
+// using op. Multiple conditions are joined with AND.
+func Where(column string, op string, value any) Clause {
+	return func(q *query) {
+		q.where = append(q.where, condition{column, strings.ToLower(op), value})
+	}
+}
+
+// OrderBy orders results by the given column. Multiple orderings are applied in
+// the order they are passed.
+func OrderBy(column string, order Order) Clause {
+	return func(q *query) {
+		q.order = append(q.order, ordering{column, order})
+	}
+}
+
+// Limit restricts the number of returned rows to at most n.
+func Limit(n int) Clause {
+	return func(q *query) {
+		q.limit = n
+	}
+}
+
+// Offset skips the first n rows of the result.
+func Offset(n int) Clause {
+	return func(q *query) {
+		q.offset = n
+	}
+}
+
+// build validates all clauses against the given columns and returns the
+// resulting SQL (appended to an existing statement) together with its
+// parameters.
+func (q *query) build(columns []string) (string, []any, error) {
+	var sb strings.Builder
+	var args []any
+
+	for i, c := range q.where {
+		if !slices.Contains(columns, c.Column) {
+			return "", nil, fmt.Errorf("invalid column `%s`", c.Column)
+		}
+		if !slices.Contains(operators, c.Op) {
+			return "", nil, fmt.Errorf("invalid operator `%s`", c.Op)
+		}
+
+		if i == 0 {
+			sb.WriteString(" where ")
+		} else {
+			sb.WriteString(" and ")
+		}
+		fmt.Fprintf(&sb, "%s %s ?", c.Column, c.Op)
+		args = append(args, c.Value)
+	}
+
+	for i, o := range q.order {
+		if !slices.Contains(columns, o.Column) {
+			return "", nil, fmt.Errorf("invalid column `%s`", o.Column)
+		}
+		if o.Order != Asc && o.Order != Desc {
+			return "", nil, fmt.Errorf("invalid order `%s`", o.Order)
+		}
+
+		if i == 0 {
+			sb.WriteString(" order by ")
+		} else {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%s %s", o.Column, o.Order)
+	}
+
+	if q.limit < 0 || q.offset < 0 {
+		return "", nil, fmt.Errorf("invalid limit or offset (%d, %d)", q.limit, q.offset)
+	}
+
+	// SQLite only allows OFFSET as part of a LIMIT-clause. A negative limit
+	// denotes no upper bound.
+	if q.limit > 0 || q.offset > 0 {
+		limit := q.limit
+		if limit == 0 {
+			limit = -1
+		}
+
+		sb.WriteString(" limit ? offset ?")
+		args = append(args, limit, q.offset)
+	}
+
+	return sb.String(), args, nil
+}
@@ -105,7 +105,8 @@ func (r *Repository[T]) WithTx(tx Tx) *Repository[T] {
 	return &Repository[T]{tx, r.Tbl, r.columns, r.fields, r.values}
 }
 
-// Get fetches and returns the database entry with the given ID.
+// Get fetches and returns the database entry with the given ID. Returns
+// [ErrNotFound] if no such entry exists.
 func (r *Repository[T]) Get(id string) (T, error) {
 	log.Info("Getting entity", "tbl", r.Tbl, "id", id)
 	stmt := fmt.Sprintf("select %s from %s where id = ?", strings.Join(r.columns, ","), r.Tbl)
@@ -115,7 +116,7 @@ func (r *Repository[T]) Get(id string) (T, error) {
 	row := r.conn.QueryRow(stmt, id)
 	if err := row.Scan(r.scanT(t)...); err != nil {
 		log.Error("Get failed", "tbl", r.Tbl, "id", id, "stmt", stmt, "err", err)
-		return t, err
+		return t, mapErr(err)
 	}
 
 	log.Debug("Get complete", "tbl", r.Tbl, "id", id, "t", &t)
@@ -130,7 +131,7 @@ func (r *Repository[T]) GetAll() ([]T, error) {
 	rows, err := r.conn.Query(stmt)
 	if err != nil {
 		log.Debug("Get all failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
-		return nil, err
+		return nil, mapErr(err)
 	}
 	defer rows.Close()
 
@@ -140,7 +141,7 @@ func (r *Repository[T]) GetAll() ([]T, error) {
 
 		if err := rows.Scan(r.scanT(t)...); err != nil {
 			log.Error("Get all scan failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
-			return nil, err
+			return nil, mapErr(err)
 		}
 
 		s = append(s, t)
@@ -150,22 +151,66 @@ func (r *Repository[T]) GetAll() ([]T, error) {
 	return s, nil
 }
 
-// Create creates a new database entry with the given ID and data.
+// Find fetches all entries matching the given clauses (see [Where], [OrderBy],
+// [Limit] and [Offset]). Column names used in clauses must match one of the
+// repository's columns.
+func (r *Repository[T]) Find(clauses ...Clause) ([]T, error) {
+	log.Info("Finding entities", "tbl", r.Tbl)
+
+	q := &query{}
+	for _, c := range clauses {
+		c(q)
+	}
+
+	cond, args, err := q.build(r.columns)
+	if err != nil {
+		log.Error("Find failed", "tbl", r.Tbl, "err", err)
+		return nil, err
+	}
+	stmt := fmt.Sprintf("select %s from %s%s", strings.Join(r.columns, ","), r.Tbl, cond)
+
+	rows, err := r.conn.Query(stmt, args...)
+	if err != nil {
+		log.Error("Find failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var s []T
+	for rows.Next() {
+		t := r.getT()
+
+		if err := rows.Scan(r.scanT(t)...); err != nil {
+			log.Error("Find scan failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
+			return nil, mapErr(err)
+		}
+
+		s = append(s, t)
+	}
+
+	log.Debug("Find complete", "tbl", r.Tbl, "stmt", stmt, "entities", len(s))
+	return s, nil
+}
+
+// Create creates a new database entry with the given ID and data. Returns
+// [ErrDuplicate] if an entry with the same key already exists.
 func (r *Repository[T]) Create(id string, t T) error {
 	log.Info("Creating entity", "tbl", r.Tbl, "id", id, "entity", t)
-	stmt := fmt.Sprintf("insert into %s values (%s)", r.Tbl, r.values)
+	// Columns are named explicitly, since columns added by migrations may not
+	// match the order of fields.
+	stmt := fmt.Sprintf("insert into %s (%s) values (%s)", r.Tbl, strings.Join(r.columns, ","), r.values)
 
 	if _, err := r.conn.Exec(stmt, r.scanT(t)...); err != nil {
 		log.Error("Create failed", "tbl", r.Tbl, "id", id, "entity", t, "stmt", stmt, "err", err)
-		return err
+		return mapErr(err)
 	}
 
 	log.Debug("Create complete", "tbl", r.Tbl, "id", id, "entity", t)
 	return nil
 }
 
-// Update updates the database entry with the given ID. Returns an error if no
-// such entry exists.
+// Update updates the database entry with the given ID. Returns [ErrNotFound] if
+// no such entry exists.
 func (r *Repository[T]) Update(id string, t T) error {
 	log.Info("Updating entity", "tbl", r.Tbl, "id", id, "entity", t)
 	stmt := fmt.Sprintf("update %s set (%s) = (%s) where id = ?", r.Tbl, strings.Join(r.columns, ","), r.values)
@@ -173,17 +218,54 @@ func (r *Repository[T]) Update(id string, t T) error {
 	res, err := r.conn.Exec(stmt, append(r.scanT(t), id)...)
 	if err != nil {
 		log.Error("Update failed", "tbl", r.Tbl, "id", id, "entity", t, "stmt", stmt, "err", err)
-		return err
+		return mapErr(err)
 	}
 	if i, _ := res.RowsAffected(); i == 0 {
 		log.Error("Update failed", "tbl", r.Tbl, "id", id, "entity", t, "stmt", stmt, "err", "no rows affected")
-		return fmt.Errorf("no rows affected")
+		return fmt.Errorf("%w: no rows affected", ErrNotFound)
 	}
 
 	log.Debug("Update complete", "tbl", r.Tbl, "id", id, "entity", t)
 	return nil
 }
 
+// UpdateWhere updates all database entries matching the given clauses (see
+// [Where]) with the given data, returning the number of updated entries. This
+// allows updating entries of tables keyed by more than their ID. Only
+// WHERE-clauses are supported, at least one of which is required.
+func (r *Repository[T]) UpdateWhere(t T, clauses ...Clause) (int64, error) {
+	log.Info("Updating entities", "tbl", r.Tbl, "entity", t)
+
+	q := &query{}
+	for _, c := range clauses {
+		c(q)
+	}
+	if len(q.order) > 0 || q.limit > 0 || q.offset > 0 {
+		return 0, fmt.Errorf("unsupported clauses for update")
+	}
+	// Updating without conditions would overwrite every entry with t.
+	if len(q.where) == 0 {
+		return 0, fmt.Errorf("missing where-clause for update")
+	}
+
+	cond, args, err := q.build(r.columns)
+	if err != nil {
+		log.Error("Update where failed", "tbl", r.Tbl, "err", err)
+		return 0, err
+	}
+	stmt := fmt.Sprintf("update %s set (%s) = (%s)%s", r.Tbl, strings.Join(r.columns, ","), r.values, cond)
+
+	res, err := r.conn.Exec(stmt, append(r.scanT(t), args...)...)
+	if err != nil {
+		log.Error("Update where failed", "tbl", r.Tbl, "entity", t, "stmt", stmt, "err", err)
+		return 0, mapErr(err)
+	}
+
+	n, _ := res.RowsAffected()
+	log.Debug("Update where complete", "tbl", r.Tbl, "entity", t, "updated", n)
+	return n, nil
+}
+
 // Delete removes the database entry with the given ID.
 func (r *Repository[T]) Delete(id string) error {
 	log.Info("Deleting entity", "tbl", r.Tbl, "id", id)
@@ -191,13 +273,49 @@ func (r *Repository[T]) Delete(id string) error {
 
 	if _, err := r.conn.Exec(stmt, id); err != nil {
 		log.Error("Delete failed", "tbl", r.Tbl, "id", id, "stmt", stmt, "err", err)
-		return err
+		return mapErr(err)
 	}
 
 	log.Debug("Delete complete", "tbl", r.Tbl, "id", id)
 	return nil
 }
 
+// DeleteWhere removes all database entries matching the given clauses (see
+// [Where]), returning the number of removed entries. Only WHERE-clauses are
+// supported, at least one of which is required.
+func (r *Repository[T]) DeleteWhere(clauses ...Clause) (int64, error) {
+	log.Info("Deleting entities", "tbl", r.Tbl)
+
+	q := &query{}
+	for _, c := range clauses {
+		c(q)
+	}
+	if len(q.order) > 0 || q.limit > 0 || q.offset > 0 {
+		return 0, fmt.Errorf("unsupported clauses for delete")
+	}
+	// Deleting without conditions is reserved for [Repository.DeleteAll].
+	if len(q.where) == 0 {
+		return 0, fmt.Errorf("missing where-clause for delete")
+	}
+
+	cond, args, err := q.build(r.columns)
+	if err != nil {
+		log.Error("Delete where failed", "tbl", r.Tbl, "err", err)
+		return 0, err
+	}
+	stmt := fmt.Sprintf("delete from %s%s", r.Tbl, cond)
+
+	res, err := r.conn.Exec(stmt, args...)
+	if err != nil {
+		log.Error("Delete where failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
+		return 0, mapErr(err)
+	}
+
+	n, _ := res.RowsAffected()
+	log.Debug("Delete where complete", "tbl", r.Tbl, "deleted", n)
+	return n, nil
+}
+
 // DeleteAll removes all entries from the underlying database table.
 func (r *Repository[T]) DeleteAll() error {
 	log.Info("Deleting all entities", "tbl", r.Tbl)
@@ -205,7 +323,7 @@ func (r *Repository[T]) DeleteAll() error {
 
 	if _, err := r.conn.Exec(stmt); err != nil {
 		log.Error("Delete all failed", "tbl", r.Tbl, "stmt", stmt, "err", err)
-		return err
+		return mapErr(err)
 	}
 
 	log.Debug("Delete all complete", "tbl", r.Tbl)
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// applyDecay moves the Elo of inactive users toward the configured baseline
+// (see [Env.DecayBaseline]) for the given, completed puzzle day. Users are
+// inactive once they missed at least [Env.DecayAfter] consecutive puzzle days,
+// including the given one. Each change is recorded in the decay table.
+//
+// Decay is applied at most once per user and puzzle day. Users without any
+// recorded history never decay.
+func applyDecay(ctx context.Context, day time.Time) error {
+	if env.DecayAfter <= 0 || env.DecayAmount <= 0 {
+		return nil
+	}
+
+	date := day.Format(time.DateOnly)
+	log.Info("Applying decay", "day", date)
+
+	return dal.DB.Transaction(ctx, func(tx db.Tx) error {
+		txDecay := dal.Decay.WithTx(tx)
+		txTotal := dal.Total.WithTx(tx)
+
+		applied, err := txDecay.Find(db.Where("date", "=", date))
+		if err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(applied))
+		for _, d := range applied {
+			done[d.UserID] = true
+		}
+
+		totals, err := txTotal.Find(db.Where("last_played", "!=", ""), db.Where("last_played", "<", date))
+		if err != nil {
+			return err
+		}
+
+		var decayed int
+		for _, t := range totals {
+			if done[t.UserID] || missedDays(t.LastPlayed, date) < env.DecayAfter {
+				continue
+			}
+
+			change := decayChange(t.Elo)
+			if change == 0 {
+				continue
+			}
+
+			t.Elo += change
+			if err := txTotal.Update(t.UserID, t); err != nil {
+				return err
+			}
+
+			decay := &models.Decay{UserID: t.UserID, Date: date, EloChange: change}
+			if err := txDecay.Create(t.UserID, decay); err != nil {
+				return err
+			}
+			decayed++
+		}
+
+		log.Info("Decay applied", "day", date, "users", decayed)
+		return nil
+	})
+}
+
+// decayChange returns the change moving the given Elo toward the configured
+// baseline (see [Env.DecayBaseline]) by at most [Env.DecayAmount].
+func decayChange(elo int) int {
+	if elo > env.DecayBaseline {
+		return -min(env.DecayAmount, elo-env.DecayBaseline)
+	} else if elo < env.DecayBaseline {
+		return min(env.DecayAmount, env.DecayBaseline-elo)
+	}
+	return 0
+}
+
+// missedDays returns the number of puzzle days after the last played one, up
+// to and including the given one. Both are formatted as [time.DateOnly].
+func missedDays(lastPlayed string, date string) int {
+	last, err := time.Parse(time.DateOnly, lastPlayed)
+	if err != nil {
+		log.Warn("Invalid puzzle day", "date", lastPlayed, "err", err)
+		return 0
+	}
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		log.Warn("Invalid puzzle day", "date", date, "err", err)
+		return 0
+	}
+
+	// Dates are parsed as UTC, such that each day is exactly 24 hours.
+	return int(day.Sub(last) / (24 * time.Hour))
+}
+
+// fmtDecay formats the decay applied to the given user since they last played
+// for display in stats. Returns an empty string if no decay was applied.
+func fmtDecay(total *models.TotalStats) string {
+	decay, err := dal.Decay.Find(db.Where("id", "=", total.UserID), db.Where("date", ">", total.LastPlayed))
+	if err != nil {
+		log.Warn("Failed to retrieve decay", "uID", total.UserID, "err", err)
+		return ""
+	}
+	if len(decay) == 0 {
+		return ""
+	}
+
+	var change int
+	for _, d := range decay {
+		change += d.EloChange
+	}
+
+	return fmt.Sprintf("Decay      %+d (inactive since %s)\n", change, total.LastPlayed)
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
+	"slices"
+	"strconv"
+	"time"
+	"tons-of-stats/models"
 
 	_ "github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
@@ -31,20 +36,104 @@ type Env struct {
 	//
 	// Read from STATS_CHANNEL.
 	StatsCh string
+
+	// Quiet period to wait for after a change before updating the leaderboard.
+	// Changes within this period are combined into a single update.
+	//
+	// Read from LEADERBOARD_DELAY (e.g. "5s"; see [time.ParseDuration]).
+	LeaderboardDelay time.Duration
+
+	// Maximum time to wait after the first of consecutive changes before
+	// updating the leaderboard, such that a steady stream of changes can't delay
+	// updates indefinitely. There is no maximum if 0.
+	//
+	// Read from LEADERBOARD_MAX_DELAY (e.g. "30s"; see [time.ParseDuration]).
+	LeaderboardMaxDelay time.Duration
+
+	// Schedule of the daily LoLdle puzzle reset. Determines when daily stats are
+	// cleared and which puzzle day submissions count towards.
+	//
+	// Read from RESET_TIMEZONE (IANA name, e.g. "Europe/Paris"; defaults to the
+	// host's local time zone) and RESET_TIME (e.g. "00:00").
+	Reset Daily
+
+	// Maximum duration of an orderly shutdown, after which the application exits
+	// regardless of any outstanding work.
+	//
+	// Read from SHUTDOWN_TIMEOUT (e.g. "10s"; see [time.ParseDuration]).
+	ShutdownTimeout time.Duration
+
+	// Scoring rules used to calculate the change in Elo for each submission.
+	//
+	// Read from SCORING_FILE (path to a JSON file; see [models.LoadScoreTable]).
+	// Defaults to [models.DefaultScoreTable].
+	Scorer models.Scorer
+
+	// Ratings displayed on the leaderboard and in stats. Glicko-2 ratings are
+	// updated regardless of this setting, such that it may be changed at any
+	// time.
+	//
+	// Read from RATING_MODE ("points", "glicko" or "both"; defaults to "points").
+	RatingMode RatingMode
+
+	// Number of consecutive missed puzzle days after which a user's Elo starts
+	// to decay (see [applyDecay]). Decay is disabled if 0.
+	//
+	// Read from DECAY_AFTER (defaults to 0).
+	DecayAfter int
+
+	// Elo by which inactive users move toward the baseline per missed day.
+	//
+	// Read from DECAY_AMOUNT (defaults to 5).
+	DecayAmount int
+
+	// Elo inactive users decay toward.
+	//
+	// Read from DECAY_BASELINE (defaults to 1000).
+	DecayBaseline int
+
+	// Length of competitive seasons (see [endSeason]). Seasons are disabled if
+	// empty.
+	//
+	// Read from SEASON_LENGTH ("monthly" or "quarterly"; defaults to disabled).
+	SeasonLength SeasonLength
+
+	// Fraction by which every user's Elo is moved toward the initial Elo at the
+	// end of each season. 1 resets Elo completely.
+	//
+	// Read from SEASON_RESET (e.g. "0.5"; defaults to 0.5).
+	SeasonReset float64
+
+	// Rank tiers users are placed in based on their Elo.
+	//
+	// Read from TIERS_FILE (path to a JSON file; see [models.LoadTiers]).
+	// Defaults to [models.DefaultTiers].
+	Tiers models.Tiers
+
+	// Name of the role assigned to the user in first place (see [RoleSync]).
+	// Disabled if empty.
+	//
+	// Read from ROLE_CHAMPION.
+	ChampionRole string
+
+	// Maps tier names to the names of roles assigned to users placed in that tier
+	// or above, up to the next mapped tier (see [RoleSync]).
+	//
+	// Read from TIER_ROLES (JSON object, e.g. {"Gold IV": "LoLdle Gold"}).
+	TierRoles map[string]string
+
+	// Minimum streak of consecutive days played for which a 🔥-badge is shown
+	// next to a user's name on the leaderboard. Disabled if 0.
+	//
+	// Read from STREAK_BADGE (defaults to 0).
+	StreakBadge int
 }
 
 // NewEnv creates a new [*Env], reading required values from the environment.
 // Where possible, this function provides more-or-less (probably less) sensible
 // defaults.
 func NewEnv() *Env {
-	env := &Env{
-		ResultsCh: "result-spam",
-		StatsCh:   "daily-stats",
-	}
-
-	if v, ok := os.LookupEnv("PROD"); ok && v == "1" {
-		env.IsProd = true
-	}
+	env := NewOfflineEnv()
 
 	if v, ok := os.LookupEnv("DISCORD_BOT_TOKEN"); ok {
 		env.Token = v
@@ -57,12 +146,163 @@ func NewEnv() *Env {
 		log.Fatal("SERVER_ID not set")
 	}
 
+	return env
+}
+
+// NewOfflineEnv creates a new [*Env] for use without a connection to Discord
+// (e.g. when replaying exports; see [replay]), leaving values only required
+// for connecting empty.
+func NewOfflineEnv() *Env {
+	env := &Env{
+		ResultsCh:           "result-spam",
+		StatsCh:             "daily-stats",
+		LeaderboardDelay:    5 * time.Second,
+		LeaderboardMaxDelay: 30 * time.Second,
+		Reset:               Daily{Loc: time.Local},
+		ShutdownTimeout:     10 * time.Second,
+		Scorer:              &models.DefaultScoreTable,
+		RatingMode:          RatingPoints,
+		DecayAmount:         5,
+		DecayBaseline:       models.InitialElo,
+		SeasonReset:         0.5,
+		Tiers:               models.DefaultTiers,
+	}
+
+	if v, ok := os.LookupEnv("PROD"); ok && v == "1" {
+		env.IsProd = true
+	}
+
 	if v, ok := os.LookupEnv("RESULT_CHANNEL"); ok {
 		env.ResultsCh = v
 	}
 	if v, ok := os.LookupEnv("STATS_CHANNEL"); ok {
 		env.StatsCh = v
 	}
+	if v, ok := os.LookupEnv("LEADERBOARD_DELAY"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatal("LEADERBOARD_DELAY invalid", "value", v, "err", err)
+		}
+		env.LeaderboardDelay = d
+	}
+	if v, ok := os.LookupEnv("LEADERBOARD_MAX_DELAY"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatal("LEADERBOARD_MAX_DELAY invalid", "value", v, "err", err)
+		}
+		env.LeaderboardMaxDelay = d
+	}
+	if v, ok := os.LookupEnv("SHUTDOWN_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatal("SHUTDOWN_TIMEOUT invalid", "value", v, "err", err)
+		}
+		env.ShutdownTimeout = d
+	}
+	if v, ok := os.LookupEnv("RESET_TIMEZONE"); ok {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			log.Fatal("RESET_TIMEZONE invalid", "value", v, "err", err)
+		}
+		env.Reset.Loc = loc
+	}
+	if v, ok := os.LookupEnv("RESET_TIME"); ok {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			log.Fatal("RESET_TIME invalid", "value", v, "err", err)
+		}
+		env.Reset.Hour, env.Reset.Minute = t.Hour(), t.Minute()
+	}
+	if v, ok := os.LookupEnv("RATING_MODE"); ok {
+		switch m := RatingMode(v); m {
+		case RatingPoints, RatingGlicko, RatingBoth:
+			env.RatingMode = m
+		default:
+			log.Fatal("RATING_MODE invalid", "value", v)
+		}
+	}
+	if v, ok := os.LookupEnv("DECAY_AFTER"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("DECAY_AFTER invalid", "value", v, "err", err)
+		}
+		env.DecayAfter = n
+	}
+	if v, ok := os.LookupEnv("DECAY_AMOUNT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("DECAY_AMOUNT invalid", "value", v, "err", err)
+		}
+		env.DecayAmount = n
+	}
+	if v, ok := os.LookupEnv("DECAY_BASELINE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("DECAY_BASELINE invalid", "value", v, "err", err)
+		}
+		env.DecayBaseline = n
+	}
+	if v, ok := os.LookupEnv("STREAK_BADGE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("STREAK_BADGE invalid", "value", v, "err", err)
+		}
+		env.StreakBadge = n
+	}
+	if v, ok := os.LookupEnv("SEASON_LENGTH"); ok {
+		switch l := SeasonLength(v); l {
+		case SeasonNone, SeasonMonthly, SeasonQuarterly:
+			env.SeasonLength = l
+		default:
+			log.Fatal("SEASON_LENGTH invalid", "value", v)
+		}
+	}
+	if v, ok := os.LookupEnv("SEASON_RESET"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			log.Fatal("SEASON_RESET invalid", "value", v, "err", err)
+		}
+		env.SeasonReset = f
+	}
+	if v, ok := os.LookupEnv("TIERS_FILE"); ok {
+		fh, err := os.Open(v)
+		if err != nil {
+			log.Fatal("TIERS_FILE invalid", "value", v, "err", err)
+		}
+		defer fh.Close()
+
+		tiers, err := models.LoadTiers(fh)
+		if err != nil {
+			log.Fatal("TIERS_FILE invalid", "value", v, "err", err)
+		}
+		env.Tiers = tiers
+	}
+	if v, ok := os.LookupEnv("ROLE_CHAMPION"); ok {
+		env.ChampionRole = v
+	}
+	if v, ok := os.LookupEnv("TIER_ROLES"); ok {
+		if err := json.Unmarshal([]byte(v), &env.TierRoles); err != nil {
+			log.Fatal("TIER_ROLES invalid", "value", v, "err", err)
+		}
+		for name := range env.TierRoles {
+			if !slices.ContainsFunc(env.Tiers, func(t models.Tier) bool { return t.Name == name }) {
+				log.Fatal("TIER_ROLES invalid", "value", v, "err", "unknown tier "+name)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv("SCORING_FILE"); ok {
+		fh, err := os.Open(v)
+		if err != nil {
+			log.Fatal("SCORING_FILE invalid", "value", v, "err", err)
+		}
+		defer fh.Close()
+
+		table, err := models.LoadScoreTable(fh)
+		if err != nil {
+			log.Fatal("SCORING_FILE invalid", "value", v, "err", err)
+		}
+		env.Scorer = table
+	}
 
 	return env
 }
@@ -1,24 +1,24 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 	"tons-of-stats/db"
 	"tons-of-stats/models"
+	sess "tons-of-stats/session"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
 )
 
-// RecordStats records information about newly played LoLdle games.
+// RecordStats records information about newly played LoLdle games. Messages
+// are held back until live results are accepted (see [acceptResults]).
 //
 // [discordgo.EventHandler]
 func RecordStats(dcs *discordgo.Session, msg *discordgo.MessageCreate) {
-	if msg.Author.ID == session.AppID {
-		log.Debug("Ignoring own message", "msgID", msg.ID)
-		return
-	}
-
 	if ch, err := session.GetChannelID(env.ResultsCh); err != nil {
 		log.Debug("Ignoring message", "uID", msg.Author.ID, "err", err)
 		return
@@ -27,6 +27,103 @@ func RecordStats(dcs *discordgo.Session, msg *discordgo.MessageCreate) {
 		return
 	}
 
+	<-liveResults.accepted
+	if sess.CompareIDs(msg.ID, liveResults.lastID) <= 0 {
+		log.Debug("Ignoring processed message", "uID", msg.Author.ID, "msgID", msg.ID)
+		return
+	}
+
+	processResult(msg.Message)
+}
+
+// resultGate holds back live results until results posted during downtime have
+// been processed (see [backfill]). Registering the handler for live results
+// beforehand ensures no messages are missed in between, while the gate keeps
+// messages from being processed twice.
+type resultGate struct {
+	// Closed once live results are accepted.
+	accepted chan struct{}
+
+	// ID of the last message processed before live results were accepted.
+	lastID string
+}
+
+var liveResults = &resultGate{accepted: make(chan struct{})}
+
+// acceptResults starts processing live results held back by [RecordStats].
+// Messages up to the results channel's cursor (see [advanceCursor]) have
+// already been processed, such that they are ignored. Must be called at most
+// once.
+func acceptResults() {
+	if chID, err := session.GetChannelID(env.ResultsCh); err == nil {
+		if cursor, err := dal.Cursors.Get(chID); err == nil {
+			liveResults.lastID = cursor.MessageID
+		}
+	}
+
+	log.Info("Accepting results", "after", liveResults.lastID)
+	close(liveResults.accepted)
+}
+
+// backfill processes all messages posted to the results channel since the last
+// processed message, e.g. while the application was offline. Messages are
+// processed oldest first, counting towards the puzzle day they were posted on.
+//
+// Scheduled runs missed while offline (see [Scheduler.CatchUpUntil]) are
+// interleaved with the messages, such that each message is processed after all
+// runs scheduled before it was posted, as it would have been live. Runs missed
+// after the last message are left to the caller.
+func backfill() error {
+	chID, err := session.GetChannelID(env.ResultsCh)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := dal.Cursors.Get(chID)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+
+		// Without a cursor, there is no way to tell which messages have already
+		// been processed. Start tracking from the latest message instead.
+		log.Info("No cursor found - skipping backfill", "chID", chID)
+		msgs, err := session.MsgList(chID)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+		return advanceCursor(chID, msgs[0].ID)
+	}
+
+	log.Info("Backfilling results", "chID", chID, "after", cursor.MessageID)
+	msgs, err := session.MsgListAfter(chID, cursor.MessageID)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		if err := scheduler.CatchUpUntil(m.Timestamp); err != nil {
+			return err
+		}
+		processResult(m)
+	}
+
+	log.Info("Backfill complete", "chID", chID, "messages", len(msgs))
+	return nil
+}
+
+// processResult parses a message from the results channel and records the
+// contained stats for the message's author. Stats count towards the puzzle
+// day the message was posted on. The message is marked as processed
+// afterwards, regardless of its contents (see [advanceCursor]).
+func processResult(msg *discordgo.Message) {
+	defer advanceCursor(msg.ChannelID, msg.ID)
+
+	if msg.Author.ID == session.GetAppID() {
+		log.Debug("Ignoring own message", "msgID", msg.ID)
+		return
+	}
+
 	if !models.CanParse(msg.Content) {
 		log.Debug("Ignoring message", "uID", msg.Author.ID, "msg", msg.Content, "reason", "not parsable")
 		return
@@ -41,25 +138,109 @@ func RecordStats(dcs *discordgo.Session, msg *discordgo.MessageCreate) {
 	}
 
 	// Update daily and total stats for the message's author.
-	stats := models.NewDailyStats(msg.Author.ID, parsed)
-	if err := updateStats(stats); err != nil {
-		session.MsgReact(msg.ChannelID, msg.ID, "❌")
-	} else {
-		session.MsgReact(msg.ChannelID, msg.ID, "✅")
+	stats := models.NewDailyStats(msg.Author.ID, parsed, env.Scorer)
+	update, err := updateStats(stats, puzzleDay(msg.Timestamp))
+	if err != nil {
+		if errors.Is(err, db.ErrDuplicate) {
+			log.Info("Ignoring repeat submission", "uID", msg.Author.ID, "msgID", msg.ID)
+			session.MsgReact(msg.ChannelID, msg.ID, "🔁")
+			reply(msg, "🔁  **You already submitted your results today.**", "")
+		} else {
+			log.Error("Failed to record stats", "uID", msg.Author.ID, "msgID", msg.ID, "err", err)
+			session.MsgReact(msg.ChannelID, msg.ID, "❌")
+			reply(
+				msg,
+				"❌  **Could not record your results. Please try again.**",
+				"If this error persists, please contact the moderation team.",
+			)
+		}
+		return
+	}
+
+	session.MsgReact(msg.ChannelID, msg.ID, "✅")
+
+	announceTier(msg.Author.ID, update.EloBefore, update.EloAfter)
+	announceRecords(update.Records)
+	leaderboard.MarkDirty()
+}
+
+// Guards cursor updates, which may happen concurrently for multiple messages.
+var cursorMu sync.Mutex
+
+// advanceCursor marks the message with the given ID as the last processed
+// message of its channel, unless a newer message has already been processed.
+func advanceCursor(chID string, msgID string) error {
+	cursorMu.Lock()
+	defer cursorMu.Unlock()
+
+	cursor, err := dal.Cursors.Get(chID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return dal.Cursors.Create(chID, &models.Cursor{ChannelID: chID, MessageID: msgID})
+		}
+		return err
+	}
+
+	if sess.CompareIDs(msgID, cursor.MessageID) <= 0 {
+		return nil
+	}
+
+	cursor.MessageID = msgID
+	return dal.Cursors.Update(chID, cursor)
+}
+
+// reply responds to the given message with the given text and an optional,
+// secondary note.
+func reply(msg *discordgo.Message, text string, note string) {
+	content := text
+	if note != "" {
+		content = fmt.Sprintf("%s\n-# %s", text, note)
 	}
 
-	leaderboard.Update()
+	session.MsgSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Content:   content,
+		Reference: msg.Reference(),
+	})
+}
+
+// statsUpdate describes the effects of a submission recorded by [updateStats].
+type statsUpdate struct {
+	// Total Elo before and after the submission.
+	EloBefore int
+	EloAfter  int
+
+	// Server-wide records broken by the submission.
+	Records []recordBreak
 }
 
 // updateStats modifies the user's daily and total stats with the given stats.
-func updateStats(daily *models.DailyStats) error {
-	log.Info("Updating daily stats", "uID", daily.UserID, "stats", daily)
+// The stats are additionally recorded in the user's history for the given
+// puzzle day (see [puzzleDay]).
+//
+// Stats for past puzzle days (e.g. picked up by [backfill]) are only recorded
+// in the user's history and total stats.
+//
+// Server-wide records and personal bests are updated as well (see
+// [updateRecords]).
+func updateStats(daily *models.DailyStats, day time.Time) (update statsUpdate, err error) {
+	log.Info("Updating daily stats", "uID", daily.UserID, "day", day, "stats", daily)
+	current := !day.Before(puzzleDay(time.Now()))
 
-	err := dal.DB.Transaction(func(tx db.Tx) error {
+	err = dal.DB.Transaction(context.Background(), func(tx db.Tx) error {
 		// Update daily stats if possible. Primary key conflicts indicate duplicate
 		// submissions within the same day.
-		txToday := dal.Today.WithTx(tx)
-		if err := txToday.Create(daily.UserID, daily); err != nil {
+		if current {
+			txToday := dal.Today.WithTx(tx)
+			if err := txToday.Create(daily.UserID, daily); err != nil {
+				return err
+			}
+		}
+
+		// Keep a permanent record of the submission. Daily stats are cleared at
+		// the end of each day (see [dailyReset]). Primary key conflicts indicate
+		// duplicate submissions for past days.
+		txHistory := dal.History.WithTx(tx)
+		if err := txHistory.Create(daily.UserID, models.NewHistoryStats(day, daily)); err != nil {
 			return err
 		}
 
@@ -70,7 +251,7 @@ func updateStats(daily *models.DailyStats) error {
 		txTotal := dal.Total.WithTx(tx)
 		total, err := txTotal.Get(daily.UserID)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
+			if errors.Is(err, db.ErrNotFound) {
 				log.Info("No stats found - creating total stats", "uID", daily.UserID)
 				total = models.NewTotalStats(daily.UserID)
 
@@ -86,13 +267,27 @@ func updateStats(daily *models.DailyStats) error {
 
 		// Total stats can safely be updated here, since any violations (e.g. from
 		// multiple submissions) are caught during the first update.
+		update.EloBefore = total.Elo
 		total.Update(daily)
+		update.EloAfter = total.Elo
+		// Playing on the day after the last played one extends the streak.
+		// Submissions for earlier days (e.g. from [backfill]) don't affect it.
+		if date := day.Format(time.DateOnly); date > total.LastPlayed {
+			if total.LastPlayed == puzzleDay(day.Add(-time.Nanosecond)).Format(time.DateOnly) {
+				total.Streak++
+			} else {
+				total.Streak = 1
+			}
+			total.LongestStreak = max(total.LongestStreak, total.Streak)
+			total.LastPlayed = date
+		}
 		if err := txTotal.Update(daily.UserID, total); err != nil {
 			return err
 		}
 
-		return nil
+		update.Records, err = updateRecords(tx, daily, total, day.Format(time.DateOnly))
+		return err
 	})
 
-	return err
+	return update, err
 }
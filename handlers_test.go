@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"tons-of-stats/models"
+	sess "tons-of-stats/session"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// result is a valid LoLdle result as posted by players.
+const result = `I've completed all the modes of #LoLdle today:
+🟢 Classic: 1
+🟢 Quote: 2
+🟢 Ability: 1 ✓
+🟢 Emoji: 3
+🟢 Splash: 1 ✓`
+
+// testBot wires up the bot against a [sess.Fake], as done on startup (see
+// [main]), using a new, empty database. Returns the fake along with the IDs of
+// the results and stats channels.
+func testBot(t *testing.T) (f *sess.Fake, resultsCh string, statsCh string) {
+	t.Helper()
+
+	env = &Env{
+		ResultsCh: "result-spam",
+		StatsCh:   "daily-stats",
+		// Updates are flushed explicitly instead.
+		LeaderboardDelay: time.Hour,
+		Reset:            Daily{Loc: time.UTC},
+		ShutdownTimeout:  time.Second,
+		Scorer:           &models.DefaultScoreTable,
+		RatingMode:       RatingPoints,
+		Tiers:            models.DefaultTiers,
+	}
+	dal = newTestDAL(t)
+
+	f = sess.NewFake("bot")
+	resultsCh = f.AddChannel(env.ResultsCh)
+	statsCh = f.AddChannel(env.StatsCh)
+	f.AddMember("alice", "Alice")
+	session = f
+
+	scheduler = NewScheduler(dal, realClock{})
+
+	l, err := NewLeaderboard(dal, env, f)
+	if err != nil {
+		t.Fatalf("Failed to initialize leaderboard: %v", err)
+	}
+	leaderboard = l
+	t.Cleanup(func() { leaderboard.Stop(context.Background()) })
+
+	liveResults = &resultGate{accepted: make(chan struct{})}
+	session.HandlerAdd("record-stats", RecordStats)
+	if err := backfill(); err != nil {
+		t.Fatalf("Failed to backfill results: %v", err)
+	}
+	acceptResults()
+
+	return f, resultsCh, statsCh
+}
+
+func TestSubmission(t *testing.T) {
+	f, resultsCh, statsCh := testBot(t)
+
+	parsed, err := models.ParseStats(result)
+	if err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	elo := models.InitialElo + env.Scorer.Score(parsed)
+
+	// First submission
+	msg := f.Post(resultsCh, "alice", result)
+	today := puzzleDay(msg.Timestamp).Format(time.DateOnly)
+	if got := f.Reactions[msg.ID]; !slices.Equal(got, []string{"✅"}) {
+		t.Errorf("Reactions = %v, want [✅]", got)
+	}
+
+	total, err := dal.Total.Get("alice")
+	if err != nil {
+		t.Fatalf("Failed to get total stats: %v", err)
+	}
+	if total.Elo != elo || total.Streak != 1 || total.LastPlayed != today {
+		t.Errorf("Total stats = %+v, want Elo %d, streak 1, last played %s", total, elo, today)
+	}
+
+	history, err := dal.History.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 || history[0].UserID != "alice" || history[0].Date != today || history[0].EloChange != elo-models.InitialElo {
+		t.Errorf("History = %+v, want a single entry for alice on %s", history, today)
+	}
+
+	if _, err := dal.Today.Get("alice"); err != nil {
+		t.Errorf("Failed to get daily stats: %v", err)
+	}
+
+	// Leaderboard
+	if err := leaderboard.Flush(); err != nil {
+		t.Fatalf("Failed to update leaderboard: %v", err)
+	}
+	lb, err := f.MsgGet(statsCh, leaderboard.msgID)
+	if err != nil {
+		t.Fatalf("Failed to get leaderboard: %v", err)
+	}
+	if lb.Content != lbHeader || len(lb.Embeds) == 0 {
+		t.Fatalf("Leaderboard = %q with %d embeds, want %q with a podium", lb.Content, len(lb.Embeds), lbHeader)
+	}
+	podium := lb.Embeds[0].Fields
+	if !strings.Contains(podium[1].Value, "Alice") || !strings.Contains(podium[2].Value, strconv.Itoa(elo)) {
+		t.Errorf("Podium = %q / %q, want Alice with %d Elo", podium[1].Value, podium[2].Value, elo)
+	}
+
+	// Repeated submission
+	repeat := f.Post(resultsCh, "alice", result)
+	if got := f.Reactions[repeat.ID]; !slices.Equal(got, []string{"🔁"}) {
+		t.Errorf("Reactions = %v, want [🔁]", got)
+	}
+
+	replies := slices.DeleteFunc(slices.Clone(f.Messages[resultsCh]), func(m *discordgo.Message) bool {
+		return m.MessageReference == nil || m.MessageReference.MessageID != repeat.ID
+	})
+	if len(replies) != 1 || !strings.Contains(replies[0].Content, "already submitted") {
+		t.Errorf("Replies = %v, want a single reply about the repeat submission", replies)
+	}
+
+	// Repeats don't affect any stats.
+	if total, err := dal.Total.Get("alice"); err != nil || total.Elo != elo {
+		t.Errorf("Total stats after repeat = %+v (%v), want Elo %d", total, err, elo)
+	}
+	if history, err := dal.History.GetAll(); err != nil || len(history) != 1 {
+		t.Errorf("History after repeat = %+v (%v), want a single entry", history, err)
+	}
+}
@@ -1,60 +1,503 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
 
 	"github.com/charmbracelet/log"
 )
 
-// schedule registers a job (i.e. callback) to be run repeatedly. The first
-// invocation happens at start, from where on out the job is invoked at the
-// given interval.
+var ErrJobRunning = errors.New("scheduler: job already running")
+var ErrUnknownJob = errors.New("scheduler: unknown job")
+var ErrJobNotDue = errors.New("scheduler: job not due")
+
+// Clock provides the current time and timers to the scheduler. Replacing the
+// clock allows exercising schedules without waiting for real time to pass.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is a [Clock] backed by the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Schedule determines the points in time a job is run at.
+type Schedule interface {
+	// Next returns the first scheduled time strictly after t.
+	Next(t time.Time) time.Time
+
+	// Prev returns the last scheduled time at or before t.
+	Prev(t time.Time) time.Time
+}
+
+// Daily is a [Schedule] recurring every day at a fixed wall-clock time in the
+// given location. Scheduled times are calculated from the calendar, such that
+// they stay at the same wall-clock time across DST changes. Wall-clock times
+// skipped by a DST change are normalized as described for [time.Date].
+type Daily struct {
+	Loc    *time.Location
+	Hour   int
+	Minute int
+}
+
+// on returns the scheduled time on the given calendar day.
+func (d Daily) on(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, d.Hour, d.Minute, 0, 0, d.Loc)
+}
+
+func (d Daily) Next(t time.Time) time.Time {
+	t = t.In(d.Loc)
+
+	next := d.on(t.Year(), t.Month(), t.Day())
+	if !next.After(t) {
+		next = d.on(t.Year(), t.Month(), t.Day()+1)
+	}
+
+	return next
+}
+
+func (d Daily) Prev(t time.Time) time.Time {
+	t = t.In(d.Loc)
+
+	prev := d.on(t.Year(), t.Month(), t.Day())
+	if prev.After(t) {
+		prev = d.on(t.Year(), t.Month(), t.Day()-1)
+	}
+
+	return prev
+}
+
+// puzzleDay returns the start of the puzzle day the given time falls on, i.e.
+// the last daily reset at or before t (see [Env.Reset]).
+func puzzleDay(t time.Time) time.Time {
+	return env.Reset.Prev(t)
+}
+
+// Job is a task run by the [Scheduler]. Jobs receive the time they were
+// scheduled for, which may lie in the past for runs that are caught up on (see
+// [Scheduler.CatchUp]). The context is canceled once the scheduler stops.
+type Job func(ctx context.Context, at time.Time) error
+
+// JobStatus describes the state of a registered job.
+type JobStatus struct {
+	Name    string
+	Running bool
+
+	// Next scheduled run. Zero until the scheduler is started.
+	Next time.Time
+
+	// Start, end and outcome of the last completed run, if any.
+	LastStart    time.Time
+	LastEnd      time.Time
+	LastDuration time.Duration
+	LastErr      error
+}
+
+// job is a job registered with a [Scheduler].
+type job struct {
+	name  string
+	sched Schedule
+	fn    Job
+
+	// Held for the duration of each run, preventing overlapping runs.
+	running sync.Mutex
+
+	// Guarded by the scheduler's mutex.
+	status JobStatus
+}
+
+// Scheduler runs named jobs according to their schedules. The last successful
+// scheduled run of each job is persisted, such that runs missed during
+// downtime can be caught up on.
+type Scheduler struct {
+	dal   *DAL
+	clock Clock
+
+	mu   sync.Mutex
+	jobs map[string]*job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler without any jobs.
+func NewScheduler(dal *DAL, clock Clock) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		dal:    dal,
+		clock:  clock,
+		jobs:   make(map[string]*job),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register adds a job run at the times determined by sched. Names must be
+// unique and are used to persist the job's runs.
+func (s *Scheduler) Register(name string, sched Schedule, fn Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job with name `%s` already exists", name)
+	}
+
+	log.Info("Job registered", "job", name)
+	s.jobs[name] = &job{name: name, sched: sched, fn: fn, status: JobStatus{Name: name}}
+	return nil
+}
+
+// Names returns the names of all registered jobs in alphabetical order.
+func (s *Scheduler) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Status returns the status of all registered jobs, ordered by name.
+func (s *Scheduler) Status() []JobStatus {
+	names := s.Names()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]JobStatus, 0, len(names))
+	for _, name := range names {
+		status = append(status, s.jobs[name].status)
+	}
+
+	return status
+}
+
+// CatchUp runs all invocations of registered jobs missed since their last
+// successful runs, oldest first. Jobs are caught up on one after another, in
+// alphabetical order.
 //
-// The job is always run at least once, when the start time elapses. If the
-// start time is in the past, the first invocation occurs immediately. If
-// interval is 0, the function exits after this first invocation.
-func schedule(start time.Time, interval time.Duration, job func()) {
-	delay := time.Until(start)
-	log.Info("Scheduling job", "job", job, "delay", delay.Round(time.Second), "interval", interval)
-
-	timer := time.NewTimer(delay)
-	<-timer.C
-
-	// First job invocation after initial delay.
-	log.Info("Running job", "job", job)
+// Jobs without any recorded runs are assumed to be new, such that no runs are
+// missed. Their most recent scheduled run is recorded instead.
+func (s *Scheduler) CatchUp() error {
+	return s.CatchUpUntil(s.clock.Now())
+}
+
+// CatchUpUntil is like [Scheduler.CatchUp], but only runs invocations scheduled
+// at or before t. This allows interleaving missed runs with other work missed
+// during downtime (see [backfill]).
+func (s *Scheduler) CatchUpUntil(t time.Time) error {
+	for _, name := range s.Names() {
+		if err := s.catchUp(s.jobs[name], t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// catchUp runs all invocations of j missed since its last successful run,
+// which were scheduled at or before until.
+func (s *Scheduler) catchUp(j *job, until time.Time) error {
+	log.Debug("Checking for missed runs", "job", j.name, "until", until)
+	now := s.clock.Now()
+
+	last, err := s.dal.Jobs.Get(j.name)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return s.recordRun(j.name, j.sched.Prev(now))
+		}
+		return err
+	}
+
+	// Runs scheduled in the future are never caught up on early.
+	if until.After(now) {
+		until = now
+	}
+
+	var missed []time.Time
+	for at := j.sched.Next(last.LastRun); !at.After(until); at = j.sched.Next(at) {
+		missed = append(missed, at)
+	}
+
+	if len(missed) > 0 {
+		log.Warn("Catching up on missed runs", "job", j.name, "lastRun", last.LastRun, "missed", len(missed))
+	}
+	for _, at := range missed {
+		if err := s.run(j, at, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start begins running all registered jobs according to their schedules.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop cancels all scheduled and running jobs and waits for running jobs to
+// return, or until ctx is done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	log.Info("Stopping scheduler")
+	s.cancel()
+
+	done := make(chan struct{})
 	go func() {
-		job()
-		log.Debug("Job complete", "job", job)
+		s.wg.Wait()
+		close(done)
 	}()
 
-	if interval == 0 {
-		return
+	select {
+	case <-done:
+		log.Debug("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger immediately runs all invocations of the job with the given name
+// missed since its last successful run in the background (see
+// [Scheduler.CatchUp]), e.g. after a failed run. Returns [ErrJobRunning] if the
+// job is already running, or [ErrJobNotDue] if no runs were missed. Jobs are
+// never run ahead of schedule, since they may perform work meant to happen once
+// per scheduled run (see [dailyReset]).
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	running := ok && j.status.Running
+	s.mu.Unlock()
+
+	// Overlapping runs are ultimately prevented by the job itself (see
+	// [Scheduler.run]). Checking here allows reporting them to the caller.
+	if !ok {
+		return fmt.Errorf("%w: `%s`", ErrUnknownJob, name)
+	}
+	if running {
+		return ErrJobRunning
+	}
+
+	now := s.clock.Now()
+	last, err := s.dal.Jobs.Get(name)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+	// Jobs without any recorded runs are new (see [Scheduler.CatchUp]).
+	if err != nil || !last.LastRun.Before(j.sched.Prev(now)) {
+		return ErrJobNotDue
 	}
 
-	// Repeat invocations on every tick.
-	ticker := time.NewTicker(interval)
+	log.Info("Triggering job", "job", name)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.catchUp(j, now)
+	}()
+
+	return nil
+}
+
+// loop runs j at each of its scheduled times until the scheduler stops. Each
+// run is calculated anew from the schedule, rather than by a fixed interval.
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	at := j.sched.Next(s.clock.Now())
 	for {
-		<-ticker.C
-		log.Info("Running job", "job", job)
-		go func() {
-			job()
-			log.Debug("Job complete", "job", job)
-		}()
+		s.mu.Lock()
+		j.status.Next = at
+		s.mu.Unlock()
+
+		delay := at.Sub(s.clock.Now())
+		log.Info("Scheduling job", "job", j.name, "at", at, "delay", delay.Round(time.Second))
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.clock.After(delay):
+		}
+
+		s.wg.Add(1)
+		go func(at time.Time) {
+			defer s.wg.Done()
+			s.run(j, at, true)
+		}(at)
+
+		// Guard against clocks running behind the scheduled time (e.g. due to
+		// adjustments of the system clock), which would repeat the same run.
+		at = j.sched.Next(later(s.clock.Now(), at))
+	}
+}
+
+// run runs j for the given scheduled time, updating its status. Successful
+// runs are recorded as the job's last run if record is set. Returns
+// [ErrJobRunning] without running the job if it is already running.
+func (s *Scheduler) run(j *job, at time.Time, record bool) error {
+	if !j.running.TryLock() {
+		log.Warn("Skipping run of running job", "job", j.name, "at", at)
+		return ErrJobRunning
+	}
+	defer j.running.Unlock()
+
+	start := s.clock.Now()
+	s.mu.Lock()
+	j.status.Running = true
+	s.mu.Unlock()
+
+	log.Info("Running job", "job", j.name, "at", at)
+	err := j.fn(s.ctx, at)
+
+	end := s.clock.Now()
+	s.mu.Lock()
+	j.status.Running = false
+	j.status.LastStart = start
+	j.status.LastEnd = end
+	j.status.LastDuration = end.Sub(start)
+	j.status.LastErr = err
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Error("Job failed", "job", j.name, "at", at, "err", err)
+		return err
+	}
+
+	if record {
+		if err := s.recordRun(j.name, at); err != nil {
+			log.Error("Failed to record job run", "job", j.name, "at", at, "err", err)
+			return err
+		}
+	}
+
+	log.Debug("Job complete", "job", j.name, "at", at, "duration", end.Sub(start))
+	return nil
+}
+
+// recordRun stores the given time as the last successful run of a job.
+func (s *Scheduler) recordRun(name string, at time.Time) error {
+	run := &models.JobRun{Name: name, LastRun: at}
+
+	err := s.dal.Jobs.Update(name, run)
+	if errors.Is(err, db.ErrNotFound) {
+		return s.dal.Jobs.Create(name, run)
+	}
+
+	return err
+}
+
+// later returns the later of the two given times.
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// fmtJobStatus formats the status of a job for display.
+func fmtJobStatus(st JobStatus) string {
+	var sb strings.Builder
+
+	state := "idle"
+	if st.Running {
+		state = "running"
+	}
+	fmt.Fprintf(&sb, "**%s**  ·  %s\n", st.Name, state)
+
+	if !st.Next.IsZero() {
+		fmt.Fprintf(&sb, "-# Next run: <t:%d:f>\n", st.Next.Unix())
+	}
+
+	if st.LastStart.IsZero() {
+		sb.WriteString("-# Last run: never (since startup)\n")
+	} else if st.LastErr != nil {
+		fmt.Fprintf(&sb, "-# Last run: <t:%d:f> (%s) ❌ %v\n", st.LastStart.Unix(), st.LastDuration.Round(time.Millisecond), st.LastErr)
+	} else {
+		fmt.Fprintf(&sb, "-# Last run: <t:%d:f> (%s) ✅\n", st.LastStart.Unix(), st.LastDuration.Round(time.Millisecond))
 	}
+
+	return sb.String()
 }
 
-// dailyReset specifies the work to be performed by the bot once a day at
-// midnight.
-func dailyReset() {
-	log.Info("Performing daily reset")
+// dailyReset specifies the work to be performed by the bot once a day, when
+// the LoLdle puzzle resets (see [Env.Reset]).
+//
+// [Job]
+func dailyReset(ctx context.Context, at time.Time) error {
+	log.Info("Performing daily reset", "at", at)
+
+	// Close the puzzle day that ended with the reset.
+	if err := closeDay(ctx, puzzleDay(puzzleDay(at).Add(-time.Nanosecond))); err != nil {
+		return err
+	}
 
 	// Delete all entries from the daily stats table. This is necessary, since we
 	// use primary key conflicts in the database layer to detect repeat
 	// submissions within the same day. Using a separate data structure does not
-	// offer persistance across application restarts.
+	// offer persistance across application restarts. Past submissions remain
+	// available through the history table.
 	if err := dal.Today.DeleteAll(); err != nil {
 		log.Error("Failed to clear daily stats", "table", dal.Today.Tbl, "err", err)
+		return err
+	}
+
+	// Updating the leaderboard also reconciles roles with the new standings (see
+	// [RoleSync]). Manual runs may happen before the leaderboard is set up.
+	if leaderboard != nil {
+		leaderboard.Flush()
+	}
+	return nil
+}
+
+// closeDay performs all work depending on the complete results of the given
+// puzzle day, i.e. updating ratings (see [updateRatings]), applying decay (see
+// [applyDecay]), breaking streaks (see [breakStreaks]) and ending seasons (see
+// [endSeason]). Closing a day more than once has no further effect.
+//
+// Closing stops early once ctx is done. Each step completes or is rolled back
+// as a whole, such that the remaining steps are performed when closing the
+// day again.
+func closeDay(ctx context.Context, day time.Time) error {
+	steps := []struct {
+		name string
+		fn   func(ctx context.Context, day time.Time) error
+	}{
+		{"update ratings", updateRatings},
+		{"apply decay", applyDecay},
+		{"break streaks", breakStreaks},
+		{"end season", endSeason},
+	}
+
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			log.Warn("Closing day canceled", "day", day, "before", s.name, "err", err)
+			return err
+		}
+		if err := s.fn(ctx, day); err != nil {
+			log.Error("Failed to "+s.name, "day", day, "err", err)
+			return err
+		}
 	}
 
-	leaderboard.Update()
+	return nil
 }
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"tons-of-stats/db"
+
+	_ "time/tzdata"
+)
+
+// fakeClock is a [Clock] whose time only changes when set explicitly (see
+// [fakeClock.Set]). The deadline of each timer created through the clock is
+// reported on deadlines.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+
+	deadlines chan time.Time
+}
+
+// fakeTimer is a timer created by [fakeClock.After], firing at the given time.
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, deadlines: make(chan time.Time, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := fakeTimer{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+
+	c.deadlines <- t.at
+	return t.ch
+}
+
+// Set sets the clock to the given time, firing all timers due by then.
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- now
+	}
+	c.timers = pending
+}
+
+// nextDeadline waits for the next timer to be created and returns its
+// deadline.
+func (c *fakeClock) nextDeadline(t *testing.T) time.Time {
+	t.Helper()
+
+	select {
+	case at := <-c.deadlines:
+		return at
+	case <-time.After(5 * time.Second):
+		t.Fatal("No timer created")
+		return time.Time{}
+	}
+}
+
+// newTestDAL creates a data access layer for a new, empty database, which is
+// closed once the test completes.
+func newTestDAL(t *testing.T) *DAL {
+	t.Helper()
+
+	d, err := db.NewDB(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(d.Close)
+
+	return NewDAL(d)
+}
+
+// berlin returns the Europe/Berlin time zone, which switches to daylight saving
+// time on 2026-03-29 at 02:00 and back on 2026-10-25 at 03:00.
+func berlin(t *testing.T) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	return loc
+}
+
+func TestDailyNext(t *testing.T) {
+	loc := berlin(t)
+
+	tests := []struct {
+		name  string
+		sched Daily
+		from  time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "spring forward",
+			sched: Daily{Loc: loc},
+			from:  time.Date(2026, 3, 28, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 29, 0, 0, 0, 0, loc),
+				time.Date(2026, 3, 30, 0, 0, 0, 0, loc), // 23 hours later
+				time.Date(2026, 3, 31, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			name:  "fall back",
+			sched: Daily{Loc: loc},
+			from:  time.Date(2026, 10, 24, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 10, 25, 0, 0, 0, 0, loc),
+				time.Date(2026, 10, 26, 0, 0, 0, 0, loc), // 25 hours later
+				time.Date(2026, 10, 27, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			name:  "skipped hour",
+			sched: Daily{Loc: loc, Hour: 2, Minute: 30},
+			from:  time.Date(2026, 3, 28, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				// 02:30 does not exist and is normalized to 03:30 CEST.
+				time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+				time.Date(2026, 3, 30, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:  "repeated hour",
+			sched: Daily{Loc: loc, Hour: 2, Minute: 30},
+			from:  time.Date(2026, 10, 24, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 10, 25, 2, 30, 0, 0, loc),
+				time.Date(2026, 10, 26, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:  "at scheduled time",
+			sched: Daily{Loc: loc, Hour: 9},
+			from:  time.Date(2026, 3, 29, 9, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 30, 9, 0, 0, 0, loc),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := tt.from
+			for _, want := range tt.want {
+				next := tt.sched.Next(at)
+				if !next.Equal(want) {
+					t.Fatalf("Next(%v) = %v, want %v", at, next, want)
+				}
+				at = next
+			}
+		})
+	}
+}
+
+func TestDailyPrev(t *testing.T) {
+	loc := berlin(t)
+
+	tests := []struct {
+		name  string
+		sched Daily
+		from  time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "spring forward",
+			sched: Daily{Loc: loc},
+			from:  time.Date(2026, 3, 30, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 30, 0, 0, 0, 0, loc),
+				time.Date(2026, 3, 29, 0, 0, 0, 0, loc),
+				time.Date(2026, 3, 28, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			name:  "fall back",
+			sched: Daily{Loc: loc},
+			from:  time.Date(2026, 10, 26, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 10, 26, 0, 0, 0, 0, loc),
+				time.Date(2026, 10, 25, 0, 0, 0, 0, loc),
+				time.Date(2026, 10, 24, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			name:  "skipped hour",
+			sched: Daily{Loc: loc, Hour: 2, Minute: 30},
+			from:  time.Date(2026, 3, 30, 0, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+				time.Date(2026, 3, 28, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:  "within skipped hour",
+			sched: Daily{Loc: loc, Hour: 2, Minute: 30},
+			// 03:15 CEST directly follows 01:59 CET, but precedes the normalized
+			// run at 03:30 CEST.
+			from: time.Date(2026, 3, 29, 3, 15, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 28, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:  "at scheduled time",
+			sched: Daily{Loc: loc, Hour: 9},
+			from:  time.Date(2026, 3, 29, 9, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 29, 9, 0, 0, 0, loc),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := tt.from
+			for _, want := range tt.want {
+				prev := tt.sched.Prev(at)
+				if !prev.Equal(want) {
+					t.Fatalf("Prev(%v) = %v, want %v", at, prev, want)
+				}
+				at = prev.Add(-time.Nanosecond)
+			}
+		})
+	}
+}
+
+// recordingJob returns a [Job] recording the times it is run for, failing for
+// any of the given times.
+func recordingJob(fail ...time.Time) (Job, func() []time.Time) {
+	var mu sync.Mutex
+	var runs []time.Time
+
+	job := func(ctx context.Context, at time.Time) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		runs = append(runs, at)
+		for _, f := range fail {
+			if at.Equal(f) {
+				return errors.New("job failed")
+			}
+		}
+		return nil
+	}
+
+	return job, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return runs
+	}
+}
+
+// lastRun returns the last recorded run of the job with the given name.
+func lastRun(t *testing.T, s *Scheduler, name string) time.Time {
+	t.Helper()
+
+	run, err := s.dal.Jobs.Get(name)
+	if err != nil {
+		t.Fatalf("Failed to get last run of `%s`: %v", name, err)
+	}
+
+	return run.LastRun
+}
+
+// equalTimes reports whether both slices contain the same instants in the
+// same order.
+func equalTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	loc := berlin(t)
+	sched := Daily{Loc: loc}
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, loc) }
+
+	t.Run("missed days", func(t *testing.T) {
+		clock := newFakeClock(day(30).Add(12 * time.Hour))
+		s := NewScheduler(newTestDAL(t), clock)
+
+		job, runs := recordingJob()
+		s.Register("reset", sched, job)
+		s.recordRun("reset", day(26))
+
+		if err := s.CatchUp(); err != nil {
+			t.Fatalf("CatchUp() failed: %v", err)
+		}
+
+		want := []time.Time{day(27), day(28), day(29), day(30)}
+		if got := runs(); !equalTimes(got, want) {
+			t.Errorf("Runs = %v, want %v", got, want)
+		}
+		if got := lastRun(t, s, "reset"); !got.Equal(day(30)) {
+			t.Errorf("Last run = %v, want %v", got, day(30))
+		}
+
+		// All missed runs have been recorded, such that nothing is run again.
+		if err := s.CatchUp(); err != nil {
+			t.Fatalf("CatchUp() failed: %v", err)
+		}
+		if got := runs(); len(got) != len(want) {
+			t.Errorf("Runs after repeated catch-up = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("until", func(t *testing.T) {
+		clock := newFakeClock(day(30).Add(12 * time.Hour))
+		s := NewScheduler(newTestDAL(t), clock)
+
+		job, runs := recordingJob()
+		s.Register("reset", sched, job)
+		s.recordRun("reset", day(26))
+
+		// Runs scheduled exactly at the given time are included.
+		if err := s.CatchUpUntil(day(28)); err != nil {
+			t.Fatalf("CatchUpUntil() failed: %v", err)
+		}
+		if want := []time.Time{day(27), day(28)}; !equalTimes(runs(), want) {
+			t.Errorf("Runs = %v, want %v", runs(), want)
+		}
+
+		// Runs are never caught up on ahead of the clock.
+		if err := s.CatchUpUntil(day(31).Add(12 * time.Hour)); err != nil {
+			t.Fatalf("CatchUpUntil() failed: %v", err)
+		}
+		if want := []time.Time{day(27), day(28), day(29), day(30)}; !equalTimes(runs(), want) {
+			t.Errorf("Runs = %v, want %v", runs(), want)
+		}
+	})
+
+	t.Run("new job", func(t *testing.T) {
+		clock := newFakeClock(day(30).Add(12 * time.Hour))
+		s := NewScheduler(newTestDAL(t), clock)
+
+		job, runs := recordingJob()
+		s.Register("reset", sched, job)
+
+		if err := s.CatchUp(); err != nil {
+			t.Fatalf("CatchUp() failed: %v", err)
+		}
+
+		if got := runs(); len(got) != 0 {
+			t.Errorf("Runs = %v, want none", got)
+		}
+		if got := lastRun(t, s, "reset"); !got.Equal(day(30)) {
+			t.Errorf("Last run = %v, want %v", got, day(30))
+		}
+	})
+
+	t.Run("failed run", func(t *testing.T) {
+		clock := newFakeClock(day(30).Add(12 * time.Hour))
+		s := NewScheduler(newTestDAL(t), clock)
+
+		job, runs := recordingJob(day(28))
+		s.Register("reset", sched, job)
+		s.recordRun("reset", day(26))
+
+		if err := s.CatchUp(); err == nil {
+			t.Fatal("CatchUp() succeeded, want error")
+		}
+
+		// Catching up stops at the failed run, which is retried next time.
+		if want := []time.Time{day(27), day(28)}; !equalTimes(runs(), want) {
+			t.Errorf("Runs = %v, want %v", runs(), want)
+		}
+		if got := lastRun(t, s, "reset"); !got.Equal(day(27)) {
+			t.Errorf("Last run = %v, want %v", got, day(27))
+		}
+	})
+}
+
+func TestSchedulerLoop(t *testing.T) {
+	loc := berlin(t)
+	sched := Daily{Loc: loc}
+
+	clock := newFakeClock(time.Date(2026, 3, 28, 23, 0, 0, 0, loc))
+	s := NewScheduler(newTestDAL(t), clock)
+
+	ran := make(chan time.Time, 1)
+	s.Register("reset", sched, func(ctx context.Context, at time.Time) error {
+		ran <- at
+		return nil
+	})
+
+	s.Start()
+	t.Cleanup(func() { s.Stop(context.Background()) })
+
+	// Runs are calculated from the calendar, such that the day of the DST
+	// change is only 23 hours long.
+	want := []time.Time{
+		time.Date(2026, 3, 29, 0, 0, 0, 0, loc),
+		time.Date(2026, 3, 30, 0, 0, 0, 0, loc),
+		time.Date(2026, 3, 31, 0, 0, 0, 0, loc),
+	}
+	for i, at := range want {
+		if got := clock.nextDeadline(t); !got.Equal(at) {
+			t.Fatalf("Timer %d set for %v, want %v", i, got, at)
+		}
+		if st := s.Status()[0]; !st.Next.Equal(at) {
+			t.Errorf("Next run = %v, want %v", st.Next, at)
+		}
+
+		clock.Set(at)
+
+		select {
+		case got := <-ran:
+			if !got.Equal(at) {
+				t.Errorf("Run %d at %v, want %v", i, got, at)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Run %d at %v did not happen", i, at)
+		}
+
+		// Wait for the run to complete, such that the next one isn't skipped as
+		// overlapping.
+		j := s.jobs["reset"]
+		j.running.Lock()
+		j.running.Unlock()
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	loc := berlin(t)
+	clock := newFakeClock(time.Date(2026, 3, 28, 23, 0, 0, 0, loc))
+	s := NewScheduler(newTestDAL(t), clock)
+
+	started := make(chan struct{})
+	s.Register("reset", Daily{Loc: loc}, func(ctx context.Context, at time.Time) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// Only missed runs can be triggered.
+	if err := s.recordRun("reset", time.Date(2026, 3, 27, 0, 0, 0, 0, loc)); err != nil {
+		t.Fatalf("Failed to record run: %v", err)
+	}
+	if err := s.Trigger("reset"); err != nil {
+		t.Fatalf("Trigger() failed: %v", err)
+	}
+	<-started
+
+	// Running jobs are canceled, such that stopping doesn't wait for them to
+	// complete on their own.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+}
+
+func TestSchedulerTriggerNotDue(t *testing.T) {
+	loc := berlin(t)
+	clock := newFakeClock(time.Date(2026, 3, 28, 12, 0, 0, 0, loc))
+	s := NewScheduler(newTestDAL(t), clock)
+
+	s.Register("reset", Daily{Loc: loc}, func(ctx context.Context, at time.Time) error {
+		t.Errorf("Job ran at %v, want no runs", at)
+		return nil
+	})
+	if err := s.CatchUp(); err != nil {
+		t.Fatalf("CatchUp() failed: %v", err)
+	}
+
+	// The most recent scheduled run was recorded by catching up, such that
+	// running the job now would run it ahead of schedule.
+	if err := s.Trigger("reset"); !errors.Is(err, ErrJobNotDue) {
+		t.Errorf("Trigger() = %v, want %v", err, ErrJobNotDue)
+	}
+}
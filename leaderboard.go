@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"tons-of-stats/db"
 	"tons-of-stats/models"
 	sess "tons-of-stats/session"
 
@@ -14,22 +17,55 @@ import (
 )
 
 var ErrNoMsg = errors.New("leaderboard: no suitable message found")
+var ErrStopped = errors.New("leaderboard: stopped")
 var lbHeader = "## Leaderboard"
 var favicon = "https://loldle.net/favicon.ico"
 
+// Custom ID prefix for the leaderboard's navigation components.
+const lbComponent = "leaderboard"
+
+const (
+	// Number of players displayed on the podium.
+	podiumSize = 3
+	// Number of players displayed on each page of the ladder. Pages must stay
+	// within Discord's limit of 1024 characters per embed field.
+	pageSize = 10
+)
+
 type Leaderboard struct {
 	dal     *DAL
 	env     *Env
-	session *sess.Session
+	session sess.Client
 
 	// Channel ID to use for retrieving and posting messages.
 	chID string
 	// Message ID of the message displaying the leaderboard.
 	msgID string
+
+	// Snapshot of the standings and user names from the last update, ordered by
+	// rank. Used to render pages without refetching (see [Leaderboard.render]).
+	// Season is 0 if seasons are disabled.
+	mu        sync.Mutex
+	standings []*models.Standing
+	names     map[string]string
+	season    int
+	updated   time.Time
+
+	// Functions called with the fresh standings after each update (see
+	// [Leaderboard.OnUpdate]). Guarded by mu.
+	hooks []func(standings []*models.Standing)
+
+	// Signals pending changes to the update loop (see [Leaderboard.run]).
+	dirty chan struct{}
+	// Requests immediate updates from the update loop, receiving the result.
+	flush chan chan error
+	// Closed to stop the update loop, which closes done once it returns.
+	stop chan struct{}
+	done chan struct{}
 }
 
 // NewLeaderboard creates a new Leaderboard.
-func NewLeaderboard(dal *DAL, env *Env, session *sess.Session) (*Leaderboard, error) {
+func NewLeaderboard(dal *DAL, env *Env, session sess.Client) (*Leaderboard, error) {
 	chID, err := session.GetChannelID(env.StatsCh)
 	if err != nil {
 		return nil, err
@@ -49,11 +85,139 @@ func NewLeaderboard(dal *DAL, env *Env, session *sess.Session) (*Leaderboard, er
 		log.Info("Reusing existing leaderboard", "msgID", msgID)
 	}
 
-	return &Leaderboard{dal, env, session, chID, msgID}, nil
+	l := &Leaderboard{
+		dal:     dal,
+		env:     env,
+		session: session,
+		chID:    chID,
+		msgID:   msgID,
+		dirty:   make(chan struct{}, 1),
+		flush:   make(chan chan error),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := session.ComponentAdd(lbComponent, l.navigate); err != nil {
+		return nil, err
+	}
+
+	go l.run()
+	return l, nil
+}
+
+// MarkDirty signals that the displayed stats have changed. The leaderboard is
+// updated once no further changes occurred for the configured quiet period
+// (see [Env.LeaderboardDelay]), such that bursts of changes only result in a
+// single update. Updates are delayed by at most [Env.LeaderboardMaxDelay]
+// after the first change. Never blocks.
+func (l *Leaderboard) MarkDirty() {
+	select {
+	case l.dirty <- struct{}{}:
+	default: // an update is already pending
+	}
+}
+
+// Flush immediately updates the leaderboard, discarding any pending delayed
+// update. Blocks until the update completes. Returns [ErrStopped] if the
+// leaderboard has been stopped.
+func (l *Leaderboard) Flush() error {
+	res := make(chan error)
+	select {
+	case l.flush <- res:
+		return <-res
+	case <-l.done:
+		return ErrStopped
+	}
+}
+
+// Stop stops the update loop, performing any pending update first. Blocks
+// until the loop returns, or until ctx is done.
+func (l *Leaderboard) Stop(ctx context.Context) error {
+	log.Info("Stopping leaderboard updates")
+	close(l.stop)
+
+	select {
+	case <-l.done:
+		log.Debug("Leaderboard updates stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run is the update loop serializing all leaderboard updates. Change signals
+// (see [Leaderboard.MarkDirty]) restart the quiet period, after which a single
+// update is performed. The quiet period never extends past the maximum delay
+// counted from the first change of an update.
+func (l *Leaderboard) run() {
+	defer close(l.done)
+
+	timer := time.NewTimer(l.env.LeaderboardDelay)
+	timer.Stop()
+	pending := false
+	// Latest time of the pending update, if there is a maximum delay.
+	var deadline time.Time
+
+	for {
+		select {
+		case <-l.dirty:
+			if !pending && l.env.LeaderboardMaxDelay > 0 {
+				deadline = time.Now().Add(l.env.LeaderboardMaxDelay)
+			}
+
+			delay := l.env.LeaderboardDelay
+			if !deadline.IsZero() {
+				delay = max(min(delay, time.Until(deadline)), 0)
+			}
+
+			log.Debug("Leaderboard marked dirty", "delay", delay)
+			timer.Reset(delay)
+			pending = true
+
+		case <-timer.C:
+			l.Update()
+			pending = false
+			deadline = time.Time{}
+
+		case res := <-l.flush:
+			timer.Stop()
+			res <- l.Update()
+			pending = false
+			deadline = time.Time{}
+
+		case <-l.stop:
+			timer.Stop()
+
+			// Changes may have been signaled, but not yet picked up.
+			select {
+			case <-l.dirty:
+				pending = true
+			default:
+			}
+
+			if pending {
+				l.Update()
+			}
+			return
+		}
+	}
+}
+
+// OnUpdate registers a function to be called with the current standings,
+// ordered by rank, after each update. Functions are called from the update
+// loop, such that slow functions delay further updates.
+func (l *Leaderboard) OnUpdate(fn func(standings []*models.Standing)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, fn)
 }
 
 // Update updates the leaderboard with the currently available user stats to
-// reflect any potential changes.
+// reflect any potential changes. The public leaderboard message always shows
+// the first page of the ladder.
+//
+// Update performs the update synchronously. Callers reacting to individual
+// changes should prefer [Leaderboard.MarkDirty] or [Leaderboard.Flush].
 func (l *Leaderboard) Update() error {
 	log.Info("Updating leaderboard", "chID", l.chID, "msgID", l.msgID)
 	if err := l.invalidateMsg(); err != nil {
@@ -61,25 +225,112 @@ func (l *Leaderboard) Update() error {
 		return err
 	}
 
-	// PERF: prefetch + cache
-	stats, err := l.dal.Total.GetAll()
+	if err := l.refresh(); err != nil {
+		log.Warn("Update failed", "chID", l.chID, "msgID", l.msgID, "err", err)
+		return err
+	}
+
+	embeds, components := l.render(0)
+	edit := &discordgo.MessageEdit{
+		Channel:    l.chID,
+		ID:         l.msgID,
+		Content:    &lbHeader,
+		Embeds:     &embeds,
+		Components: &components,
+	}
+	if _, err := l.session.MsgEditComplex(edit); err != nil {
+		log.Warn("Update failed", "chID", l.chID, "msgID", l.msgID, "err", err)
+		return err
+	}
+
+	log.Debug("Update complete", "chID", l.chID, "msgID", l.msgID)
+
+	l.mu.Lock()
+	standings, hooks := l.standings, l.hooks
+	l.mu.Unlock()
+	for _, fn := range hooks {
+		fn(standings)
+	}
+
+	return nil
+}
+
+// refresh fetches the current standings and user names, replacing the cached
+// snapshot used for rendering.
+func (l *Leaderboard) refresh() error {
+	// Standings are ordered by Elo (or rating, if only ratings are displayed),
+	// with ties broken by user ID to keep the order stable across updates.
+	// Rendering requires a single query, independent of the number of players.
+	// Names are resolved in bulk and cached by the session.
+	order := "elo"
+	if !l.env.RatingMode.ShowPoints() {
+		order = "rating"
+	}
+	standings, err := l.dal.Standings.Find(db.OrderBy(order, db.Desc), db.OrderBy("id", db.Asc))
 	if err != nil {
 		return err
 	}
 
-	var pRank, pName, pElo []string
-	var rank, name, elo []string
-	if len(stats) > 3 {
-		pRank, pName, pElo = fmtStats(stats[:3])
-		rank, name, elo = fmtStats(stats[3:])
-	} else {
-		pRank, pName, pElo = fmtStats(stats)
+	ids := make([]string, 0, len(standings))
+	for _, s := range standings {
+		ids = append(ids, s.UserID)
+	}
+	names := l.session.GetUserNames(ids)
+
+	var season int
+	if l.env.SeasonLength != SeasonNone {
+		if season, _, err = currentSeason(l.dal.Seasons); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.standings = standings
+	l.names = names
+	l.season = season
+	l.updated = time.Now()
+	return nil
+}
+
+// pages returns the number of ladder pages for the cached snapshot. Callers
+// must hold l.mu.
+func (l *Leaderboard) pages() int {
+	ladder := max(len(l.standings)-podiumSize, 0)
+	return max((ladder+pageSize-1)/pageSize, 1)
+}
+
+// render creates the leaderboard embeds and navigation components for the
+// given ladder page from the cached snapshot. Pages are counted from 0 and
+// clamped to the available range.
+func (l *Leaderboard) render(page int) ([]*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pages := l.pages()
+	page = min(max(page, 0), pages-1)
+
+	podium := l.standings[:min(podiumSize, len(l.standings))]
+	ladder := l.standings[len(podium):]
+	ladder = ladder[min(page*pageSize, len(ladder)):min((page+1)*pageSize, len(ladder))]
+
+	pRank, pName, pElo := fmtStats(podium, 0, l.names, l.env)
+	rank, name, elo := fmtStats(ladder, len(podium)+page*pageSize, l.names, l.env)
+	score := fmtScoreHeader(l.env.RatingMode)
+
+	// The leaderboard message's content identifies it (see [findMsg]). Seasons
+	// are displayed as part of the first embed instead.
+	var header *discordgo.MessageEmbedAuthor
+	if l.season > 0 {
+		header = &discordgo.MessageEmbedAuthor{Name: fmt.Sprintf("Season %d", l.season)}
 	}
 
 	embeds := []*discordgo.MessageEmbed{
 		{
+			Author:      header,
 			Title:       "Podium",
-			Description: fmt.Sprintf("-# Last Update: %s", time.Now().Format(time.DateOnly+" at "+time.Kitchen)),
+			Description: fmt.Sprintf("-# Last Update: %s", l.updated.Format(time.DateOnly+" at "+time.Kitchen)),
 			Color:       ACCENT,
 			// FIX: image shows up for one frame, then disappears. Potentially
 			// relevant: discord/discord-api-docs/issues/6171.
@@ -96,7 +347,7 @@ func (l *Leaderboard) Update() error {
 					Inline: true,
 				},
 				{
-					Name:   "Elo",
+					Name:   score,
 					Value:  strings.Join(pElo, "\n"),
 					Inline: true,
 				},
@@ -104,45 +355,103 @@ func (l *Leaderboard) Update() error {
 		},
 	}
 
-	// TODO: pagination
-	if len(stats) > 3 {
-		embeds = append(embeds, &discordgo.MessageEmbed{
-			Title: "Ranked Ladder",
-			Color: ACCENT,
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name:   "Rank",
-					Value:  strings.Join(rank, "\n"),
-					Inline: true,
-				},
-				{
-					Name:   "Name",
-					Value:  strings.Join(name, "\n"),
-					Inline: true,
+	if len(ladder) == 0 {
+		return embeds, []discordgo.MessageComponent{}
+	}
+
+	embeds = append(embeds, &discordgo.MessageEmbed{
+		Title: "Ranked Ladder",
+		Color: ACCENT,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Rank",
+				Value:  strings.Join(rank, "\n"),
+				Inline: true,
+			},
+			{
+				Name:   "Name",
+				Value:  strings.Join(name, "\n"),
+				Inline: true,
+			},
+			{
+				Name:   score,
+				Value:  strings.Join(elo, "\n"),
+				Inline: true,
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d / %d", page+1, pages),
+		},
+	},
+	)
+
+	if pages == 1 {
+		return embeds, []discordgo.MessageComponent{}
+	}
+
+	// Navigation buttons encode the page they were rendered on, such that the
+	// target page can be determined when clicked (see [Leaderboard.navigate]).
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == 0,
+					CustomID: sess.CustomID(lbComponent, "prev", strconv.Itoa(page)),
 				},
-				{
-					Name:   "Elo",
-					Value:  strings.Join(elo, "\n"),
-					Inline: true,
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == pages-1,
+					CustomID: sess.CustomID(lbComponent, "next", strconv.Itoa(page)),
 				},
 			},
 		},
-		)
 	}
 
-	edit := &discordgo.MessageEdit{
-		Channel: l.chID,
-		ID:      l.msgID,
-		Content: &lbHeader,
-		Embeds:  &embeds,
+	return embeds, components
+}
+
+// navigate handles clicks on the leaderboard's navigation buttons.
+//
+// Clicks on the public leaderboard message create an ephemeral copy showing
+// the requested page, leaving the public message on the first page. Clicks on
+// ephemeral copies update the copy in place.
+//
+// [sess.ComponentHandler]
+func (l *Leaderboard) navigate(dcs *discordgo.Session, i *discordgo.Interaction, state []string) *discordgo.InteractionResponse {
+	if len(state) != 2 {
+		log.Warn("Invalid navigation state", "state", state)
+		return nil
 	}
-	if _, err := l.session.MsgEditComplex(edit); err != nil {
-		log.Warn("Update failed", "chID", l.chID, "msgID", l.msgID, "err", err)
-		return err
+
+	page, err := strconv.Atoi(state[1])
+	if err != nil {
+		log.Warn("Invalid navigation state", "state", state, "err", err)
+		return nil
 	}
 
-	log.Debug("Update complete", "chID", l.chID, "msgID", l.msgID)
-	return nil
+	switch state[0] {
+	case "prev":
+		page--
+	case "next":
+		page++
+	}
+
+	embeds, components := l.render(page)
+	data := &discordgo.InteractionResponseData{
+		Content:    lbHeader,
+		Embeds:     embeds,
+		Components: components,
+		Flags:      discordgo.MessageFlagsEphemeral,
+	}
+
+	if i.Message != nil && i.Message.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage, Data: data}
+	}
+
+	return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: data}
 }
 
 // invalidateMsg ensures the message for the stored msgID still points to a
@@ -150,7 +459,7 @@ func (l *Leaderboard) Update() error {
 // required in cases where the original leaderboard message is deleted while the
 // application is running.
 func (l *Leaderboard) invalidateMsg() error {
-	if _, err := session.MsgGet(l.chID, l.msgID); err == nil {
+	if _, err := l.session.MsgGet(l.chID, l.msgID); err == nil {
 		return nil
 	} else {
 		log.Warn("Invalid leaderboard message", "chID", l.chID, "msgID", l.msgID, "err", err)
@@ -166,7 +475,7 @@ func (l *Leaderboard) invalidateMsg() error {
 }
 
 // findMsg tries to find a pre-existing leaderboard message that can be reused.
-func findMsg(session *sess.Session, chID string) (msgID string, err error) {
+func findMsg(session sess.Client, chID string) (msgID string, err error) {
 	msgs, err := session.MsgList(chID)
 	if err != nil {
 		log.Warn("Failed to retrieve messages", "chID", chID, "err", err)
@@ -174,7 +483,7 @@ func findMsg(session *sess.Session, chID string) (msgID string, err error) {
 	}
 
 	for _, m := range msgs {
-		if m.Author.ID != session.AppID || m.Content != lbHeader {
+		if m.Author.ID != session.GetAppID() || m.Content != lbHeader {
 			continue
 		}
 
@@ -185,7 +494,7 @@ func findMsg(session *sess.Session, chID string) (msgID string, err error) {
 }
 
 // createMsg creates a new message to use as a leaderboard.
-func createMsg(session *sess.Session, chID string) (msgID string, err error) {
+func createMsg(session sess.Client, chID string) (msgID string, err error) {
 	log.Info("Creating new leaderboard")
 
 	m, err := session.MsgSendComplex(chID, &discordgo.MessageSend{Content: lbHeader})
@@ -198,49 +507,60 @@ func createMsg(session *sess.Session, chID string) (msgID string, err error) {
 	return m.ID, nil
 }
 
-// fmtStats orders and formats all user stats for display in the leaderboard.
-func fmtStats(stats []*models.TotalStats) (rank []string, name []string, elo []string) {
-	// DB ordering
-	slices.SortFunc(stats, func(a *models.TotalStats, b *models.TotalStats) int {
-		if a.Elo < b.Elo {
-			return 1
-		} else if a.Elo > b.Elo {
-			return -1
-		}
-
-		return 0
-	})
-
+// fmtStats formats the given, already ordered standings for display in the
+// leaderboard. Ranks are counted starting after the given offset. User names
+// are taken from names, which maps user IDs to their resolved names. Ratings,
+// tiers and streak badges are displayed as configured by env.
+func fmtStats(stats []*models.Standing, offset int, names map[string]string, env *Env) (rank []string, name []string, elo []string) {
 	rank = make([]string, 0, len(stats))
 	name = make([]string, 0, len(stats))
 	elo = make([]string, 0, len(stats))
 
 	for i, s := range stats {
-		user, err := session.GetUserName(s.UserID)
-		if err != nil {
-			log.Warn("Failed to resolve name", "uID", s.UserID, "err", err)
+		user, ok := names[s.UserID]
+		if !ok {
+			log.Warn("Failed to resolve name", "uID", s.UserID)
 			user = "!?unknown"
 		}
 
 		var prefix = "\x1b[30m+"
-		var change = 0
-
-		// PERF: prefetch / DB correlation
-		if daily, err := dal.Today.Get(s.UserID); err == nil {
-			if daily.EloChange > 0 {
-				prefix = "\x1b[32m+"
-			} else if daily.EloChange < 0 {
-				// Negative numbers are already prefixed when printing. As a result,
-				// this only needs to provide coloring.
-				prefix = "\x1b[31m"
-			}
-			change = daily.EloChange
+		if s.EloChange > 0 {
+			prefix = "\x1b[32m+"
+		} else if s.EloChange < 0 {
+			// Negative numbers are already prefixed when printing. As a result,
+			// this only needs to provide coloring.
+			prefix = "\x1b[31m"
+		}
+
+		var score []string
+		if env.RatingMode.ShowPoints() {
+			score = append(score, fmt.Sprintf("%4d [%s%d\x1b[0m] %s", s.Elo, prefix, s.EloChange, env.Tiers.Of(s.Elo).Name))
+		}
+		if env.RatingMode.ShowGlicko() {
+			score = append(score, fmtGlicko(s.Rating, s.RatingDeviation))
+		}
+
+		if env.StreakBadge > 0 && s.Streak >= env.StreakBadge {
+			user = fmt.Sprintf("%s 🔥%d", user, s.Streak)
 		}
 
-		rank = append(rank, fmt.Sprintf("``` %d ```", i+1))
+		rank = append(rank, fmt.Sprintf("``` %d ```", offset+i+1))
 		name = append(name, fmt.Sprintf("``` %s ```", user))
-		elo = append(elo, fmt.Sprintf("```ansi\n%4d [%s%d\x1b[0m]```", s.Elo, prefix, change))
+		elo = append(elo, fmt.Sprintf("```ansi\n%s```", strings.Join(score, "  ")))
 	}
 
 	return rank, name, elo
 }
+
+// fmtScoreHeader returns the header of the leaderboard column displaying the
+// ratings of the given mode (see [fmtStats]).
+func fmtScoreHeader(mode RatingMode) string {
+	switch mode {
+	case RatingGlicko:
+		return "Rating"
+	case RatingBoth:
+		return "Elo / Rating"
+	default:
+		return "Elo"
+	}
+}
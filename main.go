@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	"tons-of-stats/db"
 	sess "tons-of-stats/session"
@@ -16,8 +19,9 @@ var ACCENT = int(0xd6aa38)
 
 var dal *DAL
 var env *Env
-var session *sess.Session
+var session sess.Client
 var leaderboard *Leaderboard
+var scheduler *Scheduler
 
 func main() {
 	log.SetDefault(
@@ -34,6 +38,14 @@ func main() {
 		log.Warn("Failed to load .env", "err", err)
 	}
 
+	// Subcommands run without connecting to Discord.
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := replay(os.Args[2:]); err != nil {
+			log.Fatal("Replay failed", "err", err)
+		}
+		return
+	}
+
 	env = NewEnv()
 	if env.IsProd {
 		log.SetLevel(log.InfoLevel)
@@ -51,17 +63,23 @@ func main() {
 	if err != nil {
 		log.Fatal("Could not open database", "err", err)
 	}
-	defer db.Close()
 
 	dal = NewDAL(db)
 
-	// Discord session configuration
-	session = sess.NewSession(env.Token, env.ServerID)
-	if err := session.Open(cmds); err != nil {
-		log.Fatal("Failed to open session", "err", err)
+	// Jobs are registered before opening the session, such that they are
+	// available to commands. They are only run once everything is set up.
+	scheduler = NewScheduler(dal, realClock{})
+	if err := scheduler.Register("daily-reset", env.Reset, dailyReset); err != nil {
+		log.Fatal("Failed to register job", "err", err)
 	}
 
-	session.HandlerAdd("record-stats", RecordStats)
+	// Discord session configuration. Commands may run as soon as the session is
+	// opened, such that it needs to be available to them beforehand.
+	s := sess.NewSession(env.Token, env.ServerID)
+	session = s
+	if err := s.Open(cmds); err != nil {
+		log.Fatal("Failed to open session", "err", err)
+	}
 
 	// Stat display and scheduling
 	l, err := NewLeaderboard(dal, env, session)
@@ -69,16 +87,61 @@ func main() {
 		log.Fatal("Failed to initialize leaderboard", "err", err)
 	}
 	leaderboard = l
+	leaderboard.OnUpdate(NewRoleSync(env, session).Sync)
 
-	now := time.Now()
-	midnight := time.Date(
-		now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location(),
-	)
-	go schedule(midnight, 24*time.Hour, dailyReset)
+	// Results posted during downtime are processed before any new ones, along
+	// with the runs missed in between (see [backfill]). New results are already
+	// received, but held back until all work missed during downtime is done.
+	session.HandlerAdd("record-stats", RecordStats)
+	if err := backfill(); err != nil {
+		log.Error("Failed to backfill results", "err", err)
+	}
+
+	// Remaining runs missed during downtime need to complete before accepting new
+	// submissions. Otherwise, stale daily stats would reject them as repeats.
+	if err := scheduler.CatchUp(); err != nil {
+		log.Error("Failed to catch up on missed runs", "err", err)
+	}
+	scheduler.Start()
+	acceptResults()
 
 	if env.IsProd {
 		log.New(os.Stdout).Info("Running...")
 	}
 	log.Info("Running...")
-	select {}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+
+	// Restore the default behavior, such that another signal forces an exit
+	// during a slow shutdown.
+	stop()
+
+	shutdown(s, db)
+}
+
+// shutdown performs an orderly shutdown within the configured timeout (see
+// [Env.ShutdownTimeout]). New events are rejected first, before waiting for
+// in-flight work to complete. Only then are connections closed.
+func shutdown(s *sess.Session, d *db.DB) {
+	log.Info("Shutting down", "timeout", env.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), env.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.Drain(ctx); err != nil {
+		log.Warn("Failed to drain session", "err", err)
+	}
+	// Running jobs may still update the leaderboard (see [dailyReset]).
+	if err := scheduler.Stop(ctx); err != nil {
+		log.Warn("Failed to stop scheduler", "err", err)
+	}
+	if err := leaderboard.Stop(ctx); err != nil {
+		log.Warn("Failed to stop leaderboard updates", "err", err)
+	}
+	if err := s.Close(); err != nil {
+		log.Warn("Failed to close session", "err", err)
+	}
+	d.Close()
+
+	log.Info("Shutdown complete")
 }
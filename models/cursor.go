@@ -0,0 +1,7 @@
+package models
+
+// Cursor records the last processed message of a channel.
+type Cursor struct {
+	ChannelID string `db:"id"`
+	MessageID string `db:"msg_id"`
+}
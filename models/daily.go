@@ -21,8 +21,9 @@ type DailyStats struct {
 	EloChange int `db:"elo_change"`
 }
 
-// NewDailyStats creates [DailyStats] for the given user with the given stats.
-func NewDailyStats(uID string, l *LoldleStats) *DailyStats {
+// NewDailyStats creates [DailyStats] for the given user with the given stats,
+// calculating the change in Elo using the given scorer.
+func NewDailyStats(uID string, l *LoldleStats, scorer Scorer) *DailyStats {
 	return &DailyStats{
 		UserID: uID,
 
@@ -34,7 +35,20 @@ func NewDailyStats(uID string, l *LoldleStats) *DailyStats {
 		Splash:       l.Splash,
 		SplashCheck:  l.SplashCheck,
 
-		EloChange: l.CalculateElo(),
+		EloChange: scorer.Score(l),
+	}
+}
+
+// Loldle returns the game results the daily stats were created from.
+func (s *DailyStats) Loldle() *LoldleStats {
+	return &LoldleStats{
+		Classic:      s.Classic,
+		Quote:        s.Quote,
+		Ability:      s.Ability,
+		AbilityCheck: s.AbilityCheck,
+		Emoji:        s.Emoji,
+		Splash:       s.Splash,
+		SplashCheck:  s.SplashCheck,
 	}
 }
 
@@ -0,0 +1,9 @@
+package models
+
+// Decay records a change in a user's Elo caused by inactivity on a specific
+// puzzle day.
+type Decay struct {
+	UserID    string `db:"id"`
+	Date      string `db:"date"`
+	EloChange int    `db:"elo_change"`
+}
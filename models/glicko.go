@@ -0,0 +1,123 @@
+package models
+
+import "math"
+
+// Glicko-2 system parameters (see http://www.glicko.net/glicko/glicko2.pdf).
+const (
+	// Initial rating, deviation and volatility of new players.
+	GlickoRating     = 1500.0
+	GlickoDeviation  = 350.0
+	GlickoVolatility = 0.06
+
+	// Conversion factor between the Glicko and Glicko-2 scales.
+	glickoScale = 173.7178
+	// Constraint on the change in volatility over time.
+	glickoTau = 0.5
+	// Convergence tolerance of the volatility iteration.
+	glickoEpsilon = 0.000001
+)
+
+// Glicko contains a player's Glicko-2 rating. Ratings are updated once per
+// rating period, based on all games played within the period.
+type Glicko struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// NewGlicko creates a [Glicko] rating for a new player.
+func NewGlicko() Glicko {
+	return Glicko{Rating: GlickoRating, Deviation: GlickoDeviation, Volatility: GlickoVolatility}
+}
+
+// GlickoResult contains the result of a single game against an opponent. Score
+// is 1 for a win, 0.5 for a draw and 0 for a loss.
+type GlickoResult struct {
+	Opponent Glicko
+	Score    float64
+}
+
+// Update returns the rating following a rating period with the given results.
+// Opponent ratings must be those from before the period. Without any results,
+// only the deviation grows, up to the initial deviation of new players.
+func (g Glicko) Update(results []GlickoResult) Glicko {
+	mu := (g.Rating - GlickoRating) / glickoScale
+	phi := g.Deviation / glickoScale
+
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + g.Volatility*g.Volatility)
+		return Glicko{
+			Rating:     g.Rating,
+			Deviation:  min(phi*glickoScale, GlickoDeviation),
+			Volatility: g.Volatility,
+		}
+	}
+
+	// Estimated variance and improvement based on game outcomes only.
+	var vInv, sum float64
+	for _, r := range results {
+		muJ := (r.Opponent.Rating - GlickoRating) / glickoScale
+		gJ := glickoG(r.Opponent.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+
+		vInv += gJ * gJ * e * (1 - e)
+		sum += gJ * (r.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma := glickoVolatility(phi, g.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+
+	return Glicko{
+		Rating:     mu*glickoScale + GlickoRating,
+		Deviation:  min(phi*glickoScale, GlickoDeviation),
+		Volatility: sigma,
+	}
+}
+
+// glickoG reduces the impact of games against opponents with the given
+// deviation, on the Glicko-2 scale.
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// glickoVolatility determines the new volatility using the Illinois algorithm,
+// as described in step 5 of the Glicko-2 paper.
+func glickoVolatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// HistoryStats contains a single user's [DailyStats] for a specific puzzle day.
+// In contrast to [DailyStats], which only hold the results for the current day,
+// history entries are kept indefinitely.
+type HistoryStats struct {
+	UserID string `db:"id"`
+	Date   string `db:"date"`
+
+	Classic      int  `db:"classic"`
+	Quote        int  `db:"quote"`
+	Ability      int  `db:"ability"`
+	AbilityCheck bool `db:"ability_check"`
+	Emoji        int  `db:"emoji"`
+	Splash       int  `db:"splash"`
+	SplashCheck  bool `db:"splash_check"`
+
+	EloChange int `db:"elo_change"`
+}
+
+// NewHistoryStats creates [HistoryStats] from the given daily stats, recording
+// them for the puzzle day starting at the given time.
+func NewHistoryStats(day time.Time, d *DailyStats) *HistoryStats {
+	return &HistoryStats{
+		UserID: d.UserID,
+		Date:   day.Format(time.DateOnly),
+
+		Classic:      d.Classic,
+		Quote:        d.Quote,
+		Ability:      d.Ability,
+		AbilityCheck: d.AbilityCheck,
+		Emoji:        d.Emoji,
+		Splash:       d.Splash,
+		SplashCheck:  d.SplashCheck,
+
+		EloChange: d.EloChange,
+	}
+}
+
+// Loldle returns the game results the history entry was created from.
+func (h *HistoryStats) Loldle() *LoldleStats {
+	return &LoldleStats{
+		Classic:      h.Classic,
+		Quote:        h.Quote,
+		Ability:      h.Ability,
+		AbilityCheck: h.AbilityCheck,
+		Emoji:        h.Emoji,
+		Splash:       h.Splash,
+		SplashCheck:  h.SplashCheck,
+	}
+}
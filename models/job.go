@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// JobRun records the last successful run of a scheduled job.
+type JobRun struct {
+	Name    string    `db:"id"`
+	LastRun time.Time `db:"last_run"`
+}
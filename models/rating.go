@@ -0,0 +1,8 @@
+package models
+
+// RatingPeriod records a puzzle day whose results have been applied to the
+// players' ratings (see [Glicko]).
+type RatingPeriod struct {
+	Date    string `db:"id"`
+	Players int    `db:"players"`
+}
@@ -0,0 +1,53 @@
+package models
+
+// Kinds of records tracked per user and server-wide.
+const (
+	RecordEloGain    = "elo_gain"    // Highest Elo change of a single day
+	RecordOneGuesses = "one_guesses" // Most categories guessed first try in a single day
+	RecordStreak     = "streak"      // Longest streak of consecutive days played
+	RecordCheckmarks = "checkmarks"  // Most checkmarks over all days played
+	RecordPerfect    = "perfect"     // First perfect day; never broken once set
+)
+
+// Record contains the current server-wide record of a kind, together with its
+// holder and the puzzle day it was set on.
+type Record struct {
+	Kind   string `db:"id"`
+	UserID string `db:"user_id"`
+	Value  int    `db:"value"`
+	Date   string `db:"date"`
+}
+
+// PersonalBest contains a user's best value for a kind of record, together with
+// the puzzle day it was set on.
+type PersonalBest struct {
+	UserID string `db:"id"`
+	Kind   string `db:"kind"`
+	Value  int    `db:"value"`
+	Date   string `db:"date"`
+}
+
+// RecordValues returns the value of each kind of record achieved with the given
+// daily stats and the resulting total stats. Perfect days, i.e. days with all
+// categories guessed first try and both checkmarks, have a value of 1.
+func RecordValues(d *DailyStats, t *TotalStats) map[string]int {
+	var ones int
+	for _, guesses := range []int{d.Classic, d.Quote, d.Ability, d.Emoji, d.Splash} {
+		if guesses == 1 {
+			ones++
+		}
+	}
+
+	var perfect int
+	if ones == 5 && d.AbilityCheck && d.SplashCheck {
+		perfect = 1
+	}
+
+	return map[string]int{
+		RecordEloGain:    d.EloChange,
+		RecordOneGuesses: ones,
+		RecordStreak:     t.Streak,
+		RecordCheckmarks: t.AbilityCheck + t.SplashCheck,
+		RecordPerfect:    perfect,
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Scorer calculates the change in user Elo resulting from a game of LoLdle.
+type Scorer interface {
+	Score(l *LoldleStats) int
+}
+
+// ScoreTable is a table-driven [Scorer]. The points for each category are
+// listed by number of guesses, i.e. the first entry applies to a category
+// guessed on the first try. Categories with more guesses than listed net Miss
+// points instead. Guessing the ability or splash art correctly nets the
+// respective bonus.
+type ScoreTable struct {
+	Classic []int `json:"classic"`
+	Quote   []int `json:"quote"`
+	Ability []int `json:"ability"`
+	Emoji   []int `json:"emoji"`
+	Splash  []int `json:"splash"`
+
+	Miss         int `json:"miss"`
+	AbilityCheck int `json:"ability_check"`
+	SplashCheck  int `json:"splash_check"`
+}
+
+// DefaultScoreTable contains the default scoring rules, based on the
+// distribution table below, where columns correspond to the number of guesses
+// for each category.
+//
+//	|         |  1 |  2 |  3 |  4 |  5 |
+//	| ------- | -: | -: | -: | -: | -: |
+//	| Classic | +4 | +4 |  0 | -2 | -4 |
+//	| Quote   | +4 | +2 |  0 | -2 |    |
+//	| Ability | +4 | -2 |    |    |    |
+//	| Emoji   | +4 | +2 |  0 | -2 |    |
+//	| Splash  | +4 | +2 |  0 | -2 |    |
+//
+// Categories with more guesses than listed net -4 Elo each.
+// Guessing the ability or splash art correctly nets +2 Elo each.
+var DefaultScoreTable = ScoreTable{
+	Classic: []int{4, 4, 0, -2, -4},
+	Quote:   []int{4, 2, 0, -2},
+	Ability: []int{4, -2},
+	Emoji:   []int{4, 2, 0, -2},
+	Splash:  []int{4, 2, 0, -2},
+
+	Miss:         -4,
+	AbilityCheck: 2,
+	SplashCheck:  2,
+}
+
+// LoadScoreTable reads a [ScoreTable] from the given JSON. Omitted fields keep
+// their value from [DefaultScoreTable].
+func LoadScoreTable(r io.Reader) (*ScoreTable, error) {
+	// Decoding reuses the backing arrays of slices, which must not be shared with
+	// the default table.
+	t := DefaultScoreTable
+	t.Classic = slices.Clone(t.Classic)
+	t.Quote = slices.Clone(t.Quote)
+	t.Ability = slices.Clone(t.Ability)
+	t.Emoji = slices.Clone(t.Emoji)
+	t.Splash = slices.Clone(t.Splash)
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid score table: %w", err)
+	}
+
+	if len(t.Classic) == 0 || len(t.Quote) == 0 || len(t.Ability) == 0 || len(t.Emoji) == 0 || len(t.Splash) == 0 {
+		return nil, errors.New("invalid score table: categories require at least one entry")
+	}
+
+	return &t, nil
+}
+
+// Score calculates the change in user Elo resulting from the given stats.
+func (t *ScoreTable) Score(l *LoldleStats) int {
+	elo := t.points(t.Classic, l.Classic) +
+		t.points(t.Quote, l.Quote) +
+		t.points(t.Ability, l.Ability) +
+		t.points(t.Emoji, l.Emoji) +
+		t.points(t.Splash, l.Splash)
+
+	if l.AbilityCheck {
+		elo += t.AbilityCheck
+	}
+	if l.SplashCheck {
+		elo += t.SplashCheck
+	}
+
+	return elo
+}
+
+// points returns the points for a category guessed after the given number of
+// guesses.
+func (t *ScoreTable) points(points []int, guesses int) int {
+	if guesses < 1 || guesses > len(points) {
+		return t.Miss
+	}
+	return points[guesses-1]
+}
@@ -0,0 +1,19 @@
+package models
+
+// Season records a completed season by its number and the last puzzle day it
+// included.
+type Season struct {
+	Number  int    `db:"id"`
+	LastDay string `db:"last_day"`
+}
+
+// SeasonResult contains a user's final standing in a completed season. Days
+// played only include the puzzle days of the season.
+type SeasonResult struct {
+	UserID string `db:"id"`
+	Season int    `db:"season"`
+
+	Rank       int `db:"rank"`
+	Elo        int `db:"elo"`
+	DaysPlayed int `db:"days_played"`
+}
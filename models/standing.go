@@ -0,0 +1,20 @@
+package models
+
+// Standing contains the parts of a user's [TotalStats] relevant for ranking
+// them, together with the Elo change from the current day's [DailyStats].
+// Ratings and their deviation are those of the last rating period (see
+// [Glicko]).
+//
+// Standings are read-only and backed by a database view joining both tables.
+type Standing struct {
+	UserID string `db:"id"`
+
+	DaysPlayed int `db:"days_played"`
+	Elo        int `db:"elo"`
+	EloChange  int `db:"elo_change"`
+
+	Rating          float64 `db:"rating"`
+	RatingDeviation float64 `db:"rating_deviation"`
+
+	Streak int `db:"streak"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Tier is a rank tier (or division thereof) users are placed in based on their
+// Elo. Users are placed in the highest tier whose minimum Elo they reach.
+type Tier struct {
+	Name string `json:"name"`
+	Elo  int    `json:"elo"`
+}
+
+// Tiers is a threshold table of [Tier]s, ordered by ascending minimum Elo.
+type Tiers []Tier
+
+// DefaultTiers contains the default tiers, modeled after League of Legends.
+// New users start in Silver IV (see [InitialElo]), and each division spans 25
+// Elo.
+var DefaultTiers = Tiers{
+	{"Iron IV", 0},
+	{"Iron III", 825},
+	{"Iron II", 850},
+	{"Iron I", 875},
+	{"Bronze IV", 900},
+	{"Bronze III", 925},
+	{"Bronze II", 950},
+	{"Bronze I", 975},
+	{"Silver IV", 1000},
+	{"Silver III", 1025},
+	{"Silver II", 1050},
+	{"Silver I", 1075},
+	{"Gold IV", 1100},
+	{"Gold III", 1125},
+	{"Gold II", 1150},
+	{"Gold I", 1175},
+	{"Platinum IV", 1200},
+	{"Platinum III", 1225},
+	{"Platinum II", 1250},
+	{"Platinum I", 1275},
+	{"Emerald IV", 1300},
+	{"Emerald III", 1325},
+	{"Emerald II", 1350},
+	{"Emerald I", 1375},
+	{"Diamond IV", 1400},
+	{"Diamond III", 1425},
+	{"Diamond II", 1450},
+	{"Diamond I", 1475},
+	{"Master", 1500},
+	{"Grandmaster", 1600},
+	{"Challenger", 1700},
+}
+
+// LoadTiers reads [Tiers] from the given JSON array of tiers. Tiers must be
+// ordered by strictly ascending minimum Elo.
+func LoadTiers(r io.Reader) (Tiers, error) {
+	var tiers Tiers
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tiers); err != nil {
+		return nil, fmt.Errorf("invalid tiers: %w", err)
+	}
+
+	if len(tiers) == 0 {
+		return nil, errors.New("invalid tiers: at least one tier is required")
+	}
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].Elo <= tiers[i-1].Elo {
+			return nil, fmt.Errorf("invalid tiers: `%s` must require more Elo than `%s`", tiers[i].Name, tiers[i-1].Name)
+		}
+	}
+
+	return tiers, nil
+}
+
+// Index returns the index of the tier users with the given Elo are placed in.
+// Users below the lowest tier are placed in the lowest tier.
+func (t Tiers) Index(elo int) int {
+	i := 0
+	for i+1 < len(t) && t[i+1].Elo <= elo {
+		i++
+	}
+	return i
+}
+
+// Of returns the tier users with the given Elo are placed in.
+func (t Tiers) Of(elo int) Tier {
+	return t[t.Index(elo)]
+}
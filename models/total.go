@@ -19,13 +19,38 @@ type TotalStats struct {
 	Splash       int `db:"splash"`
 	SplashCheck  int `db:"splash_check"`
 
-	DaysPlayed int `db:"days_played"`
-	Elo        int `db:"elo"`
+	DaysPlayed int    `db:"days_played"`
+	Elo        int    `db:"elo"`
+	LastPlayed string `db:"last_played"` // Puzzle day (see [time.DateOnly])
+
+	// Number of consecutive puzzle days played, up to the last played one.
+	Streak        int `db:"streak"`
+	LongestStreak int `db:"longest_streak"`
+
+	// Glicko-2 rating (see [Glicko]).
+	Rating          float64 `db:"rating"`
+	RatingDeviation float64 `db:"rating_deviation"`
+	Volatility      float64 `db:"volatility"`
 }
 
+// InitialElo is the Elo of new users.
+const InitialElo = 1000
+
 // NewTotalStats creates [TotalStats] for the given user.
 func NewTotalStats(uID string) *TotalStats {
-	return &TotalStats{UserID: uID, Elo: 1000}
+	s := &TotalStats{UserID: uID, Elo: InitialElo}
+	s.SetGlicko(NewGlicko())
+	return s
+}
+
+// Glicko returns the user's Glicko-2 rating.
+func (s *TotalStats) Glicko() Glicko {
+	return Glicko{Rating: s.Rating, Deviation: s.RatingDeviation, Volatility: s.Volatility}
+}
+
+// SetGlicko replaces the user's Glicko-2 rating.
+func (s *TotalStats) SetGlicko(g Glicko) {
+	s.Rating, s.RatingDeviation, s.Volatility = g.Rating, g.Deviation, g.Volatility
 }
 
 func (s *TotalStats) String() string {
@@ -42,6 +67,7 @@ Ability    %.1f (%.2f)
 Emoji      %.1f
 Splash     %.1f (%.2f)
 DaysPlayed %d
+Streak     %d (longest %d)
 Elo        %d
 `,
 		float32(s.Classic/days),
@@ -52,6 +78,8 @@ Elo        %d
 		float32(s.Splash/days),
 		float32(s.SplashCheck/days),
 		s.DaysPlayed,
+		s.Streak,
+		s.LongestStreak,
 		s.Elo,
 	)
 }
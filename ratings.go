@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// RatingMode determines which ratings are displayed to users.
+type RatingMode string
+
+const (
+	// Display the additive Elo points calculated by the scorer (see
+	// [Env.Scorer]).
+	RatingPoints RatingMode = "points"
+	// Display Glicko-2 ratings based on head-to-head results (see
+	// [updateRatings]).
+	RatingGlicko RatingMode = "glicko"
+	// Display both.
+	RatingBoth RatingMode = "both"
+)
+
+// ShowPoints reports whether Elo points are displayed.
+func (m RatingMode) ShowPoints() bool {
+	return m != RatingGlicko
+}
+
+// ShowGlicko reports whether Glicko-2 ratings are displayed.
+func (m RatingMode) ShowGlicko() bool {
+	return m == RatingGlicko || m == RatingBoth
+}
+
+// updateRatings applies the results of the given puzzle day to all players'
+// Glicko-2 ratings, treating the day as a single rating period. Every player
+// who submitted results that day plays a game against every other such player,
+// won by whoever needed fewer guesses in total. Players who did not submit
+// results only have their rating deviation grow.
+//
+// Each puzzle day is applied at most once. Results recorded for a day after it
+// has been applied (e.g. by [backfill]) don't affect ratings.
+func updateRatings(ctx context.Context, day time.Time) error {
+	date := day.Format(time.DateOnly)
+	log.Info("Updating ratings", "day", date)
+
+	return dal.DB.Transaction(ctx, func(tx db.Tx) error {
+		txPeriods := dal.RatingPeriods.WithTx(tx)
+		txTotal := dal.Total.WithTx(tx)
+
+		if _, err := txPeriods.Get(date); err == nil {
+			log.Info("Ratings already updated", "day", date)
+			return nil
+		} else if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+
+		history, err := dal.History.WithTx(tx).Find(db.Where("date", "=", date))
+		if err != nil {
+			return err
+		}
+
+		totals, err := txTotal.GetAll()
+		if err != nil {
+			return err
+		}
+
+		// Games are rated against ratings from before the period.
+		before := make(map[string]models.Glicko, len(totals))
+		for _, t := range totals {
+			before[t.UserID] = t.Glicko()
+		}
+
+		results := make(map[string][]models.GlickoResult, len(history))
+		for _, a := range history {
+			for _, b := range history {
+				if a.UserID == b.UserID {
+					continue
+				}
+
+				results[a.UserID] = append(results[a.UserID], models.GlickoResult{
+					Opponent: before[b.UserID],
+					Score:    glickoScore(guesses(a), guesses(b)),
+				})
+			}
+		}
+
+		for _, t := range totals {
+			t.SetGlicko(before[t.UserID].Update(results[t.UserID]))
+			if err := txTotal.Update(t.UserID, t); err != nil {
+				return err
+			}
+		}
+
+		log.Info("Ratings updated", "day", date, "players", len(history))
+		return txPeriods.Create(date, &models.RatingPeriod{Date: date, Players: len(history)})
+	})
+}
+
+// guesses returns the total number of guesses over all categories.
+func guesses(h *models.HistoryStats) int {
+	return h.Classic + h.Quote + h.Ability + h.Emoji + h.Splash
+}
+
+// glickoScore returns the score of a game between players with the given
+// number of guesses, from the perspective of the first player.
+func glickoScore(a int, b int) float64 {
+	switch {
+	case a < b:
+		return 1
+	case a > b:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+// fmtGlicko formats the given Glicko-2 rating and deviation for display.
+func fmtGlicko(rating float64, deviation float64) string {
+	return fmt.Sprintf("%4.0f ±%.0f", rating, deviation)
+}
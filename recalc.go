@@ -0,0 +1,406 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// Maximum number of users listed in a recalculation report.
+const reportSize = 25
+
+// errDryRun is used to roll back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// ratingChange describes how a user's rating and placement were affected by a
+// recalculation (see [recalculate]). Ranks start at 1.
+type ratingChange struct {
+	UserID     string
+	Before     int
+	After      int
+	RankBefore int
+	RankAfter  int
+}
+
+// recalculate replays all stored submissions through the configured scorer
+// (see [Env.Scorer]), rewriting the Elo change of each history entry and daily
+// stats entry in a single transaction. Every user's total Elo is then rebuilt
+// by replaying their history (see [replayElo]), along with their decay, their
+// season results and the Elo gain records. If dryRun is set, the transaction
+// is rolled back instead.
+//
+// Users who played before history was recorded keep the contribution of those
+// games, i.e. the difference between their total Elo and the replayed one.
+// Their decay is kept as well, since it depends on games which can't be
+// replayed.
+//
+// Returns the changes for all users whose Elo or rank changed, ordered by the
+// magnitude of the change.
+func recalculate(dryRun bool) ([]ratingChange, error) {
+	log.Info("Recalculating ratings", "dryRun", dryRun)
+
+	var changes []ratingChange
+	err := dal.DB.Transaction(context.Background(), func(tx db.Tx) error {
+		txHistory := dal.History.WithTx(tx)
+		txToday := dal.Today.WithTx(tx)
+		txTotal := dal.Total.WithTx(tx)
+		txDecay := dal.Decay.WithTx(tx)
+
+		history, err := txHistory.Find(db.OrderBy("date", db.Asc), db.OrderBy("id", db.Asc))
+		if err != nil {
+			return err
+		}
+		seasons, err := dal.Seasons.WithTx(tx).GetAll()
+		if err != nil {
+			return err
+		}
+		current := puzzleDay(time.Now()).Format(time.DateOnly)
+
+		before, err := replayElo(history, seasons, current)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range history {
+			elo := env.Scorer.Score(h.Loldle())
+			if elo == h.EloChange {
+				continue
+			}
+
+			h.EloChange = elo
+			_, err := txHistory.UpdateWhere(h, db.Where("id", "=", h.UserID), db.Where("date", "=", h.Date))
+			if err != nil {
+				return err
+			}
+		}
+
+		after, err := replayElo(history, seasons, current)
+		if err != nil {
+			return err
+		}
+
+		// Daily stats are recorded in history as well, so they are already
+		// part of the replay.
+		today, err := txToday.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, d := range today {
+			if elo := env.Scorer.Score(d.Loldle()); elo != d.EloChange {
+				d.EloChange = elo
+				if err := txToday.Update(d.UserID, d); err != nil {
+					return err
+				}
+			}
+		}
+
+		played := map[string]int{}
+		for _, h := range history {
+			played[h.UserID]++
+		}
+
+		totals, err := txTotal.Find(db.OrderBy("elo", db.Desc), db.OrderBy("id", db.Asc))
+		if err != nil {
+			return err
+		}
+
+		byID := map[string]*ratingChange{}
+		carried := map[string]bool{}
+		for i, t := range totals {
+			c := &ratingChange{UserID: t.UserID, Before: t.Elo, RankBefore: i + 1, After: t.Elo}
+			byID[t.UserID] = c
+
+			elo, ok := after.Elo[t.UserID]
+			if !ok {
+				continue
+			}
+
+			// Days played before history was recorded can't be replayed.
+			if t.DaysPlayed > played[t.UserID] {
+				carried[t.UserID] = true
+				elo = max(elo+t.Elo-before.Elo[t.UserID], 0)
+			} else {
+				if _, err := txDecay.DeleteWhere(db.Where("id", "=", t.UserID)); err != nil {
+					return err
+				}
+				for _, d := range after.Decay[t.UserID] {
+					if err := txDecay.Create(t.UserID, d); err != nil {
+						return err
+					}
+				}
+			}
+
+			if elo != t.Elo {
+				t.Elo = elo
+				if err := txTotal.Update(t.UserID, t); err != nil {
+					return err
+				}
+			}
+			c.After = t.Elo
+		}
+
+		if err := recalcSeasonResults(tx, before, after, carried); err != nil {
+			return err
+		}
+		if err := recalcEloGainRecords(tx, history); err != nil {
+			return err
+		}
+
+		// Rank by the same order as the leaderboard (see [Leaderboard.refresh]).
+		slices.SortStableFunc(totals, func(a, b *models.TotalStats) int {
+			return cmp.Or(cmp.Compare(b.Elo, a.Elo), strings.Compare(a.UserID, b.UserID))
+		})
+		for i, t := range totals {
+			c := byID[t.UserID]
+			c.RankAfter = i + 1
+
+			if c.Before != c.After || c.RankBefore != c.RankAfter {
+				changes = append(changes, *c)
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		log.Error("Recalculation failed", "err", err)
+		return nil, err
+	}
+
+	slices.SortStableFunc(changes, func(a, b ratingChange) int {
+		return cmp.Or(
+			cmp.Compare(abs(b.After-b.Before), abs(a.After-a.Before)),
+			cmp.Compare(a.RankAfter, b.RankAfter),
+		)
+	})
+
+	log.Info("Recalculation complete", "dryRun", dryRun, "changed", len(changes))
+	return changes, nil
+}
+
+// eloReplay contains the outcome of replaying history (see [replayElo]).
+type eloReplay struct {
+	// Each user's total Elo.
+	Elo map[string]int
+	// Each user's decay, ordered by date.
+	Decay map[string][]*models.Decay
+	// The final Elo of each user who played during a season, by season.
+	Seasons map[int]map[string]int
+}
+
+// replayElo rebuilds the total Elo of every user in the given history, which
+// must be ordered by date. Puzzle days are replayed the same way they were
+// played: results are scored as they are submitted, after which each completed
+// day applies decay (see [applyDecay]) and, if it is the last day of one of the
+// given seasons, ends the season (see [endSeason]). Days before the given,
+// current puzzle day are completed.
+//
+// Users start out with the initial Elo once they first played.
+func replayElo(history []*models.HistoryStats, seasons []*models.Season, current string) (eloReplay, error) {
+	r := eloReplay{Elo: map[string]int{}, Decay: map[string][]*models.Decay{}, Seasons: map[int]map[string]int{}}
+	if len(history) == 0 {
+		return r, nil
+	}
+
+	ends := make(map[string]int, len(seasons))
+	for _, s := range seasons {
+		ends[s.LastDay] = s.Number
+	}
+
+	first, err := time.Parse(time.DateOnly, history[0].Date)
+	if err != nil {
+		return r, err
+	}
+	last := history[len(history)-1].Date
+
+	lastPlayed := map[string]string{}
+	played := map[string]bool{}
+	var i int
+	for day := first; ; day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		if date >= current && date > last {
+			break
+		}
+
+		for ; i < len(history) && history[i].Date <= date; i++ {
+			h := history[i]
+			elo, ok := r.Elo[h.UserID]
+			if !ok {
+				elo = models.InitialElo
+			}
+
+			// Same as [models.TotalStats.Update].
+			r.Elo[h.UserID] = max(elo+h.EloChange, 0)
+			lastPlayed[h.UserID] = date
+			played[h.UserID] = true
+		}
+
+		if date >= current {
+			continue
+		}
+
+		if env.DecayAfter > 0 && env.DecayAmount > 0 {
+			for _, uID := range slices.Sorted(maps.Keys(r.Elo)) {
+				if missedDays(lastPlayed[uID], date) < env.DecayAfter {
+					continue
+				}
+
+				if change := decayChange(r.Elo[uID]); change != 0 {
+					r.Elo[uID] += change
+					r.Decay[uID] = append(r.Decay[uID], &models.Decay{UserID: uID, Date: date, EloChange: change})
+				}
+			}
+		}
+
+		if season, ok := ends[date]; ok {
+			standings := make(map[string]int, len(played))
+			for uID := range played {
+				standings[uID] = r.Elo[uID]
+			}
+			r.Seasons[season] = standings
+			clear(played)
+
+			for uID, elo := range r.Elo {
+				r.Elo[uID] = softReset(elo)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// recalcSeasonResults rewrites the archived season results according to the
+// given replays of history before and after recalculation, re-ranking each
+// season's players. The archived Elo of carried users, i.e. users who played
+// before history was recorded, is adjusted by the difference between the
+// replays instead.
+func recalcSeasonResults(tx db.Tx, before, after eloReplay, carried map[string]bool) error {
+	txResults := dal.SeasonResults.WithTx(tx)
+
+	results, err := txResults.GetAll()
+	if err != nil {
+		return err
+	}
+
+	bySeason := map[int][]*models.SeasonResult{}
+	for _, res := range results {
+		if elo, ok := after.Seasons[res.Season][res.UserID]; ok {
+			if carried[res.UserID] {
+				elo = max(elo+res.Elo-before.Seasons[res.Season][res.UserID], 0)
+			}
+			res.Elo = elo
+		}
+		bySeason[res.Season] = append(bySeason[res.Season], res)
+	}
+
+	for season, standings := range bySeason {
+		slices.SortStableFunc(standings, func(a, b *models.SeasonResult) int {
+			return cmp.Or(cmp.Compare(b.Elo, a.Elo), strings.Compare(a.UserID, b.UserID))
+		})
+
+		for i, res := range standings {
+			res.Rank = i + 1
+			_, err := txResults.UpdateWhere(res, db.Where("id", "=", res.UserID), db.Where("season", "=", season))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recalcEloGainRecords rebuilds the server-wide Elo gain record, as well as
+// every user's personal best, from the given history, which must be ordered by
+// date. As with [updateRecords], records are only broken by strictly greater
+// values.
+func recalcEloGainRecords(tx db.Tx, history []*models.HistoryStats) error {
+	txRecords := dal.Records.WithTx(tx)
+	txBests := dal.PersonalBests.WithTx(tx)
+
+	var record *models.Record
+	bests := map[string]*models.PersonalBest{}
+	for _, h := range history {
+		if h.EloChange <= 0 {
+			continue
+		}
+
+		if b := bests[h.UserID]; b == nil || h.EloChange > b.Value {
+			bests[h.UserID] = &models.PersonalBest{UserID: h.UserID, Kind: models.RecordEloGain, Value: h.EloChange, Date: h.Date}
+		}
+		if record == nil || h.EloChange > record.Value {
+			record = &models.Record{Kind: models.RecordEloGain, UserID: h.UserID, Value: h.EloChange, Date: h.Date}
+		}
+	}
+
+	if _, err := txBests.DeleteWhere(db.Where("kind", "=", models.RecordEloGain)); err != nil {
+		return err
+	}
+	for _, uID := range slices.Sorted(maps.Keys(bests)) {
+		if err := txBests.Create(uID, bests[uID]); err != nil {
+			return err
+		}
+	}
+
+	if err := txRecords.Delete(models.RecordEloGain); err != nil {
+		return err
+	}
+	if record == nil {
+		return nil
+	}
+	return txRecords.Create(record.Kind, record)
+}
+
+// fmtRatingChanges formats a report of the given rating changes (see
+// [recalculate]), using the given user names. At most [reportSize] changes are
+// listed.
+func fmtRatingChanges(changes []ratingChange, names map[string]string) string {
+	if len(changes) == 0 {
+		return "No ratings changed."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```ansi\n")
+	for i, c := range changes {
+		if i == reportSize {
+			break
+		}
+
+		name := names[c.UserID]
+		if name == "" {
+			name = c.UserID
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"%-20.20s %5d → %-5d (%+d)  #%d → #%d\n",
+			name, c.Before, c.After, c.After-c.Before, c.RankBefore, c.RankAfter,
+		)
+	}
+	sb.WriteString("```")
+
+	if len(changes) > reportSize {
+		fmt.Fprintf(&sb, "\n-# …and %d more.", len(changes)-reportSize)
+	}
+
+	return sb.String()
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// All kinds of records in display order, together with their labels.
+var recordKinds = []struct{ Kind, Label string }{
+	{models.RecordEloGain, "Best Elo gain"},
+	{models.RecordOneGuesses, "Most first-try guesses"},
+	{models.RecordStreak, "Longest streak"},
+	{models.RecordCheckmarks, "Most checkmarks"},
+	{models.RecordPerfect, "First perfect day"},
+}
+
+// recordBreak describes a server-wide record broken by a submission. Previous
+// is nil if the record was set for the first time.
+type recordBreak struct {
+	Previous *models.Record
+	Current  *models.Record
+}
+
+// updateRecords updates the server-wide records, as well as the personal bests
+// of the daily stats' user, with the given daily stats and the resulting total
+// stats, recorded for the given puzzle day. Records require positive values and
+// are only broken by strictly greater ones. The first perfect day is never
+// broken once set.
+//
+// Returns all broken server-wide records.
+func updateRecords(tx db.Tx, daily *models.DailyStats, total *models.TotalStats, date string) ([]recordBreak, error) {
+	txRecords := dal.Records.WithTx(tx)
+	txBests := dal.PersonalBests.WithTx(tx)
+
+	values := models.RecordValues(daily, total)
+
+	var broken []recordBreak
+	for _, k := range recordKinds {
+		kind, value := k.Kind, values[k.Kind]
+		if value <= 0 {
+			continue
+		}
+
+		// Personal bests
+		best := &models.PersonalBest{UserID: daily.UserID, Kind: kind, Value: value, Date: date}
+		prevBest, err := txBests.Find(db.Where("id", "=", daily.UserID), db.Where("kind", "=", kind))
+		if err != nil {
+			return nil, err
+		}
+		if len(prevBest) == 0 {
+			if err := txBests.Create(daily.UserID, best); err != nil {
+				return nil, err
+			}
+		} else if value > prevBest[0].Value && kind != models.RecordPerfect {
+			if _, err := txBests.UpdateWhere(best, db.Where("id", "=", daily.UserID), db.Where("kind", "=", kind)); err != nil {
+				return nil, err
+			}
+		}
+
+		// Server-wide records
+		record := &models.Record{Kind: kind, UserID: daily.UserID, Value: value, Date: date}
+		prev, err := txRecords.Get(kind)
+		if err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				return nil, err
+			}
+
+			if err := txRecords.Create(kind, record); err != nil {
+				return nil, err
+			}
+			broken = append(broken, recordBreak{Current: record})
+		} else if value > prev.Value && kind != models.RecordPerfect {
+			if err := txRecords.Update(kind, record); err != nil {
+				return nil, err
+			}
+			broken = append(broken, recordBreak{Previous: prev, Current: record})
+		}
+	}
+
+	return broken, nil
+}
+
+// announceRecords posts a message in the stats channel for each of the given
+// broken records. Records that grow over time (i.e. streaks and checkmarks) are
+// only announced once they change holder, since their holders would otherwise
+// break them almost every time they play.
+func announceRecords(broken []recordBreak) {
+	broken = slices.DeleteFunc(slices.Clone(broken), func(b recordBreak) bool {
+		growing := b.Current.Kind == models.RecordStreak || b.Current.Kind == models.RecordCheckmarks
+		return growing && b.Previous != nil && b.Previous.UserID == b.Current.UserID
+	})
+	if len(broken) == 0 {
+		return
+	}
+
+	chID, err := session.GetChannelID(env.StatsCh)
+	if err != nil {
+		log.Warn("Failed to announce records", "err", err)
+		return
+	}
+
+	for _, b := range broken {
+		content := fmt.Sprintf(
+			"🏅  **New server record: %s**\n<@%s>  ·  %s",
+			recordLabel(b.Current.Kind),
+			b.Current.UserID,
+			fmtRecordValue(b.Current.Kind, b.Current.Value),
+		)
+		if b.Previous != nil {
+			content += fmt.Sprintf(
+				"\n-# Previous record: %s by <@%s>",
+				fmtRecordValue(b.Previous.Kind, b.Previous.Value),
+				b.Previous.UserID,
+			)
+		}
+
+		// Announcements mention users without notifying them.
+		_, err := session.MsgSendComplex(chID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			log.Warn("Failed to announce record", "kind", b.Current.Kind, "uID", b.Current.UserID, "err", err)
+		}
+	}
+}
+
+// recordLabel returns the display label of the given kind of record.
+func recordLabel(kind string) string {
+	for _, k := range recordKinds {
+		if k.Kind == kind {
+			return k.Label
+		}
+	}
+	return kind
+}
+
+// fmtRecordValue formats the value of the given kind of record for display.
+func fmtRecordValue(kind string, value int) string {
+	switch kind {
+	case models.RecordEloGain:
+		return fmt.Sprintf("%+d Elo", value)
+	case models.RecordOneGuesses:
+		return fmt.Sprintf("%d / 5", value)
+	case models.RecordStreak:
+		if value == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", value)
+	case models.RecordCheckmarks:
+		return fmt.Sprintf("%d", value)
+	case models.RecordPerfect:
+		return "5 / 5 ✓✓"
+	default:
+		return fmt.Sprintf("%d", value)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+	sess "tons-of-stats/session"
+
+	"github.com/charmbracelet/log"
+)
+
+// exportMsg is a single message from a channel export.
+type exportMsg struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Content   string    `json:"content"`
+	Author    struct {
+		ID string `json:"id"`
+	} `json:"author"`
+}
+
+// replay implements the "replay" subcommand, rebuilding the database from an
+// exported channel history without connecting to Discord:
+//
+//	tons-of-stats replay [-db <file>] <export.json>
+//
+// Exports are JSON files containing messages with their ID, timestamp,
+// content and author ID; either as a plain array or as an object with a
+// "messages" array (as produced by common exporters). All messages are replayed
+// in chronological order, using the same parsing and scoring as live
+// submissions. The resulting database is written to a new file.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	out := fs.String("db", "tons_of_stats.replay.sqlite", "database file to create")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tons-of-stats replay [-db <file>] <export.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("missing export file")
+	}
+
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("database file `%s` already exists", *out)
+	}
+
+	msgs, err := readExport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// Replays only log errors by default. Progress is reported separately.
+	stdout := log.New(os.Stdout)
+	log.SetLevel(log.ErrorLevel)
+
+	env = NewOfflineEnv()
+
+	d, err := db.NewDB(*out)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	dal = NewDAL(d)
+
+	stdout.Info("Replaying export", "file", fs.Arg(0), "messages", len(msgs), "db", *out)
+
+	// Days are closed once all their messages have been replayed, as they would
+	// have been by the daily reset (see [dailyReset]). This includes days
+	// without any messages.
+	var day time.Time
+	var closed int
+	closeDays := func(until time.Time) error {
+		for ; !day.IsZero() && day.Before(until); day = env.Reset.Next(day) {
+			if err := closeDay(context.Background(), day); err != nil {
+				return fmt.Errorf("closing %s: %v", day.Format(time.DateOnly), err)
+			}
+			closed++
+		}
+		return nil
+	}
+
+	var recorded, duplicates, invalid int
+	for _, m := range msgs {
+		if day.IsZero() {
+			day = puzzleDay(m.Timestamp)
+		}
+		if err := closeDays(puzzleDay(m.Timestamp)); err != nil {
+			return err
+		}
+
+		if !models.CanParse(m.Content) {
+			continue
+		}
+
+		parsed, err := models.ParseStats(m.Content)
+		if err != nil {
+			invalid++
+			continue
+		}
+
+		stats := models.NewDailyStats(m.Author.ID, parsed, env.Scorer)
+		if _, err := updateStats(stats, puzzleDay(m.Timestamp)); err != nil {
+			if errors.Is(err, db.ErrDuplicate) {
+				duplicates++
+				continue
+			}
+			return fmt.Errorf("message `%s`: %v", m.ID, err)
+		}
+
+		recorded++
+	}
+
+	if err := closeDays(puzzleDay(time.Now())); err != nil {
+		return err
+	}
+
+	stdout.Info("Replay complete", "recorded", recorded, "duplicates", duplicates, "invalid", invalid, "closed", closed)
+	return nil
+}
+
+// readExport reads all messages from the export file with the given name,
+// ordered chronologically.
+func readExport(fname string) ([]exportMsg, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []exportMsg
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &msgs)
+	} else {
+		var export struct {
+			Messages []exportMsg `json:"messages"`
+		}
+		err = json.Unmarshal(data, &export)
+		msgs = export.Messages
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid export `%s`: %v", fname, err)
+	}
+
+	// Messages with identical timestamps are ordered by their IDs.
+	slices.SortStableFunc(msgs, func(a, b exportMsg) int {
+		if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+			return c
+		}
+		return sess.CompareIDs(a.ID, b.ID)
+	})
+
+	return msgs, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"tons-of-stats/models"
+	sess "tons-of-stats/session"
+
+	"github.com/charmbracelet/log"
+)
+
+// RoleSync assigns Discord roles according to the current standings: a
+// champion role for the first rank (see [Env.ChampionRole]), and one role per
+// tier band (see [Env.TierRoles]). Only roles managed this way are changed.
+type RoleSync struct {
+	env     *Env
+	session sess.Client
+
+	// Managed role IDs last applied to each user, used to skip users whose roles
+	// are already up to date.
+	mu      sync.Mutex
+	applied map[string][]string
+
+	// Role IDs resolved on first use (see [RoleSync.resolve]). Guarded by mu.
+	resolved bool
+	champion string
+	bands    []roleBand
+}
+
+// NewRoleSync creates a new RoleSync.
+func NewRoleSync(env *Env, session sess.Client) *RoleSync {
+	return &RoleSync{env: env, session: session, applied: make(map[string][]string)}
+}
+
+// Enabled reports whether any roles are configured.
+func (r *RoleSync) Enabled() bool {
+	return r.env.ChampionRole != "" || len(r.env.TierRoles) > 0
+}
+
+// Sync reconciles the managed roles of all users in the given, already ordered
+// standings. Roles are only added or removed where they differ from the
+// desired roles.
+func (r *RoleSync) Sync(standings []*models.Standing) {
+	if !r.Enabled() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	champion, bands, err := r.resolve()
+	if err != nil {
+		log.Warn("Role sync failed", "err", err)
+		return
+	}
+
+	managed := make([]string, 0, len(bands)+1)
+	for _, b := range bands {
+		managed = append(managed, b.roleID)
+	}
+	if champion != "" {
+		managed = append(managed, champion)
+	}
+
+	var changed int
+	for i, s := range standings {
+		var want []string
+		if i == 0 && champion != "" {
+			want = append(want, champion)
+		}
+
+		// Bands are ordered by ascending tier. Users receive the role of the
+		// highest band they reached.
+		tier := r.env.Tiers.Index(s.Elo)
+		for j := len(bands) - 1; j >= 0; j-- {
+			if bands[j].tier <= tier {
+				want = append(want, bands[j].roleID)
+				break
+			}
+		}
+		slices.Sort(want)
+
+		if applied, ok := r.applied[s.UserID]; ok && slices.Equal(applied, want) {
+			continue
+		}
+
+		n, err := r.apply(s.UserID, managed, want)
+		if err != nil {
+			log.Warn("Role sync failed", "uID", s.UserID, "err", err)
+			continue
+		}
+
+		r.applied[s.UserID] = want
+		changed += n
+	}
+
+	log.Info("Role sync complete", "users", len(standings), "changed", changed)
+}
+
+// roleBand is a role assigned to users placed in the tier with the given index
+// or above (see [models.Tiers.Index]).
+type roleBand struct {
+	tier   int
+	roleID string
+}
+
+// resolve returns the ID of the champion role, if configured, as well as all
+// tier bands, ordered by tier. Roles are only looked up until they have been
+// resolved successfully, such that roles renamed afterwards require a restart.
+// Callers must hold r.mu.
+func (r *RoleSync) resolve() (champion string, bands []roleBand, err error) {
+	if r.resolved {
+		return r.champion, r.bands, nil
+	}
+
+	if r.env.ChampionRole != "" {
+		if champion, err = r.session.GetRoleID(r.env.ChampionRole); err != nil {
+			return "", nil, err
+		}
+	}
+
+	for i, t := range r.env.Tiers {
+		name, ok := r.env.TierRoles[t.Name]
+		if !ok {
+			continue
+		}
+
+		id, err := r.session.GetRoleID(name)
+		if err != nil {
+			return "", nil, err
+		}
+		bands = append(bands, roleBand{tier: i, roleID: id})
+	}
+
+	log.Info("Roles resolved", "champion", champion, "bands", len(bands))
+	r.resolved, r.champion, r.bands = true, champion, bands
+	return champion, bands, nil
+}
+
+// apply adds or removes the given managed roles of the user with the given ID,
+// such that they hold exactly the wanted ones. Returns the number of changed
+// roles.
+func (r *RoleSync) apply(uID string, managed []string, want []string) (int, error) {
+	have, err := r.session.GetMemberRoles(uID)
+	if err != nil {
+		return 0, err
+	}
+
+	var changed int
+	for _, role := range managed {
+		switch wanted, held := slices.Contains(want, role), slices.Contains(have, role); {
+		case wanted && !held:
+			log.Info("Adding role", "uID", uID, "roleID", role)
+			if err := r.session.MemberRoleAdd(uID, role); err != nil {
+				return changed, err
+			}
+			changed++
+		case !wanted && held:
+			log.Info("Removing role", "uID", uID, "roleID", role)
+			if err := r.session.MemberRoleRemove(uID, role); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
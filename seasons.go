@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+	"tons-of-stats/db"
+	"tons-of-stats/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// SeasonLength determines the length of competitive seasons.
+type SeasonLength string
+
+const (
+	// Seasons are disabled.
+	SeasonNone SeasonLength = ""
+	// Seasons end with the last puzzle day of each month.
+	SeasonMonthly SeasonLength = "monthly"
+	// Seasons end with the last puzzle day of each quarter.
+	SeasonQuarterly SeasonLength = "quarterly"
+)
+
+// months returns the number of calendar months per season, or 0 if seasons are
+// disabled.
+func (l SeasonLength) months() int {
+	switch l {
+	case SeasonMonthly:
+		return 1
+	case SeasonQuarterly:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// seasonEnd returns the start of the season following the one containing the
+// given puzzle day. Seasons must be enabled (see [Env.SeasonLength]).
+func seasonEnd(day time.Time) time.Time {
+	day = day.In(env.Reset.Loc)
+	n := env.SeasonLength.months()
+
+	month := (int(day.Month())-1)/n*n + 1 + n
+	return env.Reset.on(day.Year(), time.Month(month), 1)
+}
+
+// currentSeason returns the number of the current season, as well as the last
+// puzzle day of the previous season. The latter is empty during the first
+// season.
+func currentSeason(seasons *db.Repository[*models.Season]) (int, string, error) {
+	last, err := seasons.Find(db.OrderBy("id", db.Desc), db.Limit(1))
+	if err != nil {
+		return 0, "", err
+	}
+	if len(last) == 0 {
+		return 1, "", nil
+	}
+
+	return last[0].Number + 1, last[0].LastDay, nil
+}
+
+// endSeason ends the current season if the given, completed puzzle day is its
+// last one. The final standings of all users who played during the season are
+// archived, after which every user's Elo is moved toward the initial Elo by
+// the configured fraction (see [Env.SeasonReset]).
+//
+// Each season is ended at most once.
+func endSeason(ctx context.Context, day time.Time) error {
+	if env.SeasonLength == SeasonNone || seasonEnd(day).After(env.Reset.Next(day)) {
+		return nil
+	}
+
+	date := day.Format(time.DateOnly)
+	log.Info("Ending season", "day", date)
+
+	return dal.DB.Transaction(ctx, func(tx db.Tx) error {
+		txSeasons := dal.Seasons.WithTx(tx)
+		txResults := dal.SeasonResults.WithTx(tx)
+		txTotal := dal.Total.WithTx(tx)
+
+		if ended, err := txSeasons.Find(db.Where("last_day", "=", date)); err != nil {
+			return err
+		} else if len(ended) > 0 {
+			log.Info("Season already ended", "day", date, "season", ended[0].Number)
+			return nil
+		}
+
+		season, prev, err := currentSeason(txSeasons)
+		if err != nil {
+			return err
+		}
+
+		// Only users who played during the season are ranked.
+		history, err := dal.History.WithTx(tx).Find(db.Where("date", ">", prev), db.Where("date", "<=", date))
+		if err != nil {
+			return err
+		}
+		played := map[string]int{}
+		for _, h := range history {
+			played[h.UserID]++
+		}
+
+		totals, err := txTotal.Find(db.OrderBy("elo", db.Desc), db.OrderBy("id", db.Asc))
+		if err != nil {
+			return err
+		}
+
+		var rank int
+		for _, t := range totals {
+			if played[t.UserID] > 0 {
+				rank++
+				result := &models.SeasonResult{
+					UserID:     t.UserID,
+					Season:     season,
+					Rank:       rank,
+					Elo:        t.Elo,
+					DaysPlayed: played[t.UserID],
+				}
+				if err := txResults.Create(t.UserID, result); err != nil {
+					return err
+				}
+			}
+
+			t.Elo = softReset(t.Elo)
+			if err := txTotal.Update(t.UserID, t); err != nil {
+				return err
+			}
+		}
+
+		if err := txSeasons.Create(date, &models.Season{Number: season, LastDay: date}); err != nil {
+			return err
+		}
+
+		log.Info("Season ended", "season", season, "day", date, "players", rank)
+		return nil
+	})
+}
+
+// softReset returns the given Elo moved toward the initial Elo by the
+// configured fraction (see [Env.SeasonReset]).
+func softReset(elo int) int {
+	offset := float64(elo - models.InitialElo)
+	return models.InitialElo + int(math.Round(offset*(1-env.SeasonReset)))
+}
@@ -0,0 +1,81 @@
+package session
+
+import "github.com/bwmarrin/discordgo"
+
+// Client describes all interactions with Discord performed by the bot. It is
+// implemented by [*Session] for live connections, as well as by [*Fake] for use
+// without a network connection.
+type Client interface {
+	// GetAppID returns the application ID associated with the bot.
+	GetAppID() string
+
+	// GetChannelID returns the ID for the channel with the given name.
+	GetChannelID(name string) (string, error)
+
+	// GetUserName returns the server-local nickname for the user with the given
+	// ID.
+	GetUserName(id string) (string, error)
+
+	// GetUserNames returns the server-local nicknames for all users with the
+	// given IDs, omitting users whose names cannot be resolved.
+	GetUserNames(ids []string) map[string]string
+
+	// GetRoleID returns the ID for the role with the given name.
+	GetRoleID(name string) (string, error)
+
+	// GetMemberRoles returns the IDs of all roles of the member with the given
+	// ID.
+	GetMemberRoles(uID string) ([]string, error)
+
+	// MemberRoleAdd adds the role with the given ID to the member with the given
+	// ID.
+	MemberRoleAdd(uID string, roleID string) error
+
+	// MemberRoleRemove removes the role with the given ID from the member with the
+	// given ID.
+	MemberRoleRemove(uID string, roleID string) error
+
+	// MsgGet returns the message with the given ID from the given channel.
+	MsgGet(chID string, msgID string) (*discordgo.Message, error)
+
+	// MsgList returns as many messages as can be found from the channel with the
+	// given ID, newest first.
+	MsgList(chID string) ([]*discordgo.Message, error)
+
+	// MsgListAfter returns all messages from the channel with the given ID that
+	// were posted after the message with ID afterID, oldest first.
+	MsgListAfter(chID string, afterID string) ([]*discordgo.Message, error)
+
+	// MsgSend sends a message with contents content to the channel with ID chID.
+	MsgSend(chID string, content string) (*discordgo.Message, error)
+
+	// MsgSendComplex sends a message.
+	MsgSendComplex(chID string, send *discordgo.MessageSend) (*discordgo.Message, error)
+
+	// MsgEditComplex applies an edit to a message.
+	MsgEditComplex(edit *discordgo.MessageEdit) (*discordgo.Message, error)
+
+	// MsgReact adds a reaction to the given message, in the given channel.
+	MsgReact(chID string, msgID string, reaction string) error
+
+	// InteractionRespond responds to the given interaction. Only required by
+	// handlers responding on their own (see [Handler]).
+	InteractionRespond(i *discordgo.Interaction, resp *discordgo.InteractionResponse) error
+
+	// InteractionFollowup sends a follow-up message for the given interaction,
+	// e.g. to complete a deferred response.
+	InteractionFollowup(i *discordgo.Interaction, params *discordgo.WebhookParams) (*discordgo.Message, error)
+
+	// CommandAdd adds a new slash-command from a [Command].
+	CommandAdd(cmd Command) error
+
+	// ComponentAdd registers a handler for all message components and modals
+	// with custom IDs starting with the given prefix.
+	ComponentAdd(prefix string, h ComponentHandler) error
+
+	// HandlerAdd adds an event handler and associates it with the given name.
+	HandlerAdd(name string, handler any) error
+}
+
+var _ Client = (*Session)(nil)
+var _ Client = (*Fake)(nil)
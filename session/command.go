@@ -1,11 +1,20 @@
 package session
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
 
 // Handler represents a handler for a [*discordgo.ApplicationCommand].
 //
 // Handlers are called with the user interaction itself (i.e.
 // [*discordgo.Interaction]), not the usual [*discordgo.InteractionCreate].
+// Handlers returning nil leave the interaction unanswered, e.g. if they already
+// responded on their own (see [Client.InteractionRespond]).
 type Handler func(*discordgo.Session, *discordgo.Interaction) *discordgo.InteractionResponse
 
 // Command wraps a [*discordgo.ApplicationCommand], containing both the command
@@ -14,4 +23,166 @@ type Handler func(*discordgo.Session, *discordgo.Interaction) *discordgo.Interac
 type Command struct {
 	Definition *discordgo.ApplicationCommand
 	Handler    Handler
+
+	// Autocomplete handles autocomplete requests for any of the command's
+	// options with autocompletion enabled. May be nil if the command does not
+	// use autocompletion (see [Focused]).
+	Autocomplete Handler
+}
+
+// Subcommands creates a [Handler] routing invocations to the handler for the
+// invoked subcommand. Handlers are keyed by the subcommand's name, prefixed by
+// the name of its group (separated by a space) for grouped subcommands.
+func Subcommands(handlers map[string]Handler) Handler {
+	return func(s *discordgo.Session, i *discordgo.Interaction) *discordgo.InteractionResponse {
+		path := subcommandPath(i.ApplicationCommandData().Options)
+
+		h, ok := handlers[path]
+		if !ok {
+			log.Warn("Unknown subcommand", "name", i.ApplicationCommandData().Name, "path", path)
+			return nil
+		}
+
+		log.Debug("Routing subcommand", "name", i.ApplicationCommandData().Name, "path", path)
+		return h(s, i)
+	}
+}
+
+// subcommandPath returns the space-separated names of all subcommand groups and
+// subcommands leading up to the invoked subcommand.
+func subcommandPath(opts []*discordgo.ApplicationCommandInteractionDataOption) string {
+	var path []string
+	for len(opts) > 0 {
+		o := opts[0]
+		if o.Type != discordgo.ApplicationCommandOptionSubCommandGroup &&
+			o.Type != discordgo.ApplicationCommandOptionSubCommand {
+			break
+		}
+
+		path = append(path, o.Name)
+		opts = o.Options
+	}
+
+	return strings.Join(path, " ")
+}
+
+// Options returns the options passed to the invoked command. For subcommands,
+// these are the options of the subcommand itself, rather than the top-level
+// command.
+func Options(i *discordgo.Interaction) []*discordgo.ApplicationCommandInteractionDataOption {
+	opts := i.ApplicationCommandData().Options
+	for len(opts) > 0 {
+		o := opts[0]
+		if o.Type != discordgo.ApplicationCommandOptionSubCommandGroup &&
+			o.Type != discordgo.ApplicationCommandOptionSubCommand {
+			break
+		}
+
+		opts = o.Options
+	}
+
+	return opts
+}
+
+// Focused returns the option currently being autocompleted, or nil if there is
+// no such option.
+func Focused(i *discordgo.Interaction) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, o := range Options(i) {
+		if o.Focused {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// DecodeOptions decodes the options passed to the invoked command (see
+// [Options]) into the struct pointed to by v. Struct fields are matched to
+// options through their "option" struct-tags. Options that were not passed
+// leave the corresponding fields unchanged.
+//
+// Supported field types depend on the option type:
+//   - string: string, user, channel, role and mentionable options (IDs are
+//     used for the latter)
+//   - int and int64: integer options
+//   - float64: number options
+//   - bool: boolean options
+//
+// Choices are decoded according to the type of the option they belong to.
+func DecodeOptions(i *discordgo.Interaction, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode options into %T", v)
+	}
+	rv = rv.Elem()
+
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range Options(i) {
+		opts[o.Name] = o
+	}
+
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		name, ok := rt.Field(i).Tag.Lookup("option")
+		if !ok {
+			continue
+		}
+
+		o, ok := opts[name]
+		if !ok {
+			continue
+		}
+
+		if err := decodeOption(o, rv.Field(i)); err != nil {
+			return fmt.Errorf("option `%s`: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeOption sets f to the value of the given option. Values are asserted
+// manually, since the accessors provided by discordgo panic for mismatched
+// types (e.g. for partial input during autocompletion).
+func decodeOption(o *discordgo.ApplicationCommandInteractionDataOption, f reflect.Value) error {
+	mismatch := fmt.Errorf("cannot decode %s value %v into %s", o.Type, o.Value, f.Type())
+
+	switch o.Type {
+	case discordgo.ApplicationCommandOptionString,
+		discordgo.ApplicationCommandOptionUser,
+		discordgo.ApplicationCommandOptionChannel,
+		discordgo.ApplicationCommandOptionRole,
+		discordgo.ApplicationCommandOptionMentionable:
+		v, ok := o.Value.(string)
+		if !ok || f.Kind() != reflect.String {
+			return mismatch
+		}
+		f.SetString(v)
+
+	case discordgo.ApplicationCommandOptionInteger:
+		v, ok := o.Value.(float64) // JSON numbers are decoded as float64
+		if !ok || (f.Kind() != reflect.Int && f.Kind() != reflect.Int64) {
+			return mismatch
+		}
+		f.SetInt(int64(v))
+
+	case discordgo.ApplicationCommandOptionNumber:
+		v, ok := o.Value.(float64)
+		if !ok || f.Kind() != reflect.Float64 {
+			return mismatch
+		}
+		f.SetFloat(v)
+
+	case discordgo.ApplicationCommandOptionBoolean:
+		v, ok := o.Value.(bool)
+		if !ok || f.Kind() != reflect.Bool {
+			return mismatch
+		}
+		f.SetBool(v)
+
+	default:
+		return fmt.Errorf("unsupported option type %s", o.Type)
+	}
+
+	return nil
 }
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Separator between the prefix and state segments of a custom ID (see
+// [CustomID]).
+const idSep = ":"
+
+// ComponentHandler represents a handler for interactions with message
+// components (e.g. buttons or select menus) and modal submissions.
+//
+// Handlers are registered for a custom ID prefix and receive any additional
+// state encoded in the custom ID of the component interacted with (see
+// [CustomID]). Handlers returning nil leave the interaction unanswered.
+type ComponentHandler func(dcs *discordgo.Session, i *discordgo.Interaction, state []string) *discordgo.InteractionResponse
+
+// CustomID creates a custom ID for a message component or modal, routing
+// interactions to the handler registered for the given prefix (see
+// [Session.ComponentAdd]). Any additional state is passed to the handler.
+//
+// Neither prefix nor state may contain the separator ":". Discord limits custom
+// IDs to 100 characters.
+func CustomID(prefix string, state ...string) string {
+	return strings.Join(append([]string{prefix}, state...), idSep)
+}
+
+// ParseCustomID splits a custom ID created through [CustomID] into its prefix
+// and state.
+func ParseCustomID(id string) (prefix string, state []string) {
+	parts := strings.Split(id, idSep)
+	return parts[0], parts[1:]
+}
+
+// customIDOf returns the custom ID of the component or modal the given
+// interaction refers to. Returns false for all other interactions.
+func customIDOf(i *discordgo.Interaction) (string, bool) {
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		return i.MessageComponentData().CustomID, true
+	case discordgo.InteractionModalSubmit:
+		return i.ModalSubmitData().CustomID, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,462 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Fake is an in-memory [Client] that stores channels, members and messages
+// locally instead of connecting to Discord. Events can be delivered to
+// registered handlers through [Fake.Dispatch], allowing the bot's behavior to
+// be exercised without a network connection.
+type Fake struct {
+	mu sync.Mutex
+
+	// Application ID used as the author of all messages sent through the fake.
+	AppID string
+
+	// Maps channel names to channel IDs.
+	Channels map[string]string
+
+	// Maps user IDs to their display names.
+	Members map[string]string
+
+	// Maps role names to role IDs.
+	Roles map[string]string
+
+	// Maps user IDs to the IDs of their roles.
+	MemberRoles map[string][]string
+
+	// Maps channel IDs to all messages posted in them, oldest first.
+	Messages map[string][]*discordgo.Message
+
+	// Maps message IDs to all reactions added through the fake.
+	Reactions map[string][]string
+
+	// Maps interaction IDs to all responses sent through the fake by handlers
+	// responding on their own.
+	Responses map[string][]*discordgo.InteractionResponse
+
+	// Maps interaction IDs to all follow-up messages sent through the fake.
+	Followups map[string][]*discordgo.WebhookParams
+
+	// Maps registered command names to their commands.
+	Commands map[string]Command
+
+	// Maps registered custom ID prefixes to their component handlers.
+	Components map[string]ComponentHandler
+
+	// Maps registered event handler names to their handler functions.
+	Handlers map[string]any
+
+	// Last ID handed out for channels, roles and messages. IDs increase
+	// monotonically, mirroring the ordering of Discord's snowflake IDs.
+	lastID int
+}
+
+// NewFake creates a new, empty fake for the application with the given ID.
+func NewFake(appID string) *Fake {
+	return &Fake{
+		AppID:       appID,
+		Channels:    make(map[string]string),
+		Members:     make(map[string]string),
+		Roles:       make(map[string]string),
+		MemberRoles: make(map[string][]string),
+		Messages:    make(map[string][]*discordgo.Message),
+		Reactions:   make(map[string][]string),
+		Responses:   make(map[string][]*discordgo.InteractionResponse),
+		Followups:   make(map[string][]*discordgo.WebhookParams),
+		Commands:    make(map[string]Command),
+		Components:  make(map[string]ComponentHandler),
+		Handlers:    make(map[string]any),
+	}
+}
+
+// nextID returns a new, unique ID. Callers must hold f.mu.
+func (f *Fake) nextID() string {
+	f.lastID++
+	return strconv.Itoa(f.lastID)
+}
+
+// AddChannel creates a channel with the given name and returns its ID.
+func (f *Fake) AddChannel(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID()
+	f.Channels[name] = id
+	return id
+}
+
+// AddMember adds a server member with the given ID and display name.
+func (f *Fake) AddMember(id string, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Members[id] = name
+}
+
+// AddRole creates a role with the given name and returns its ID.
+func (f *Fake) AddRole(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID()
+	f.Roles[name] = id
+	return id
+}
+
+// Post creates a message from the given author in the given channel and
+// dispatches the corresponding [discordgo.MessageCreate] event to all
+// registered handlers.
+func (f *Fake) Post(chID string, authorID string, content string) *discordgo.Message {
+	f.mu.Lock()
+	m := &discordgo.Message{
+		ID:        f.nextID(),
+		ChannelID: chID,
+		Author:    &discordgo.User{ID: authorID},
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+	f.Messages[chID] = append(f.Messages[chID], m)
+	f.mu.Unlock()
+
+	f.Dispatch(&discordgo.MessageCreate{Message: m})
+	return m
+}
+
+// Store adds a message from the given author to the given channel without
+// dispatching any events, as if it was posted while the bot was offline.
+func (f *Fake) Store(chID string, authorID string, content string, ts time.Time) *discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := &discordgo.Message{
+		ID:        f.nextID(),
+		ChannelID: chID,
+		Author:    &discordgo.User{ID: authorID},
+		Content:   content,
+		Timestamp: ts,
+	}
+	f.Messages[chID] = append(f.Messages[chID], m)
+	return m
+}
+
+// Dispatch delivers an event to all registered handlers accepting events of
+// its type. Handlers receive a nil [*discordgo.Session].
+func (f *Fake) Dispatch(event any) {
+	f.mu.Lock()
+	handlers := make([]any, 0, len(f.Handlers))
+	for _, h := range f.Handlers {
+		handlers = append(handlers, h)
+	}
+	f.mu.Unlock()
+
+	ev := reflect.ValueOf(event)
+	for _, h := range handlers {
+		rv := reflect.ValueOf(h)
+		rt := rv.Type()
+		if rt.NumIn() != 2 || rt.In(1) != ev.Type() {
+			continue
+		}
+
+		rv.Call([]reflect.Value{reflect.Zero(rt.In(0)), ev})
+	}
+}
+
+// Interact invokes the handler responsible for the given interaction and
+// returns its response. Commands are routed to their command or autocomplete
+// handlers, while message components and modals are routed to the component
+// handler for their custom ID's prefix.
+func (f *Fake) Interact(i *discordgo.Interaction) (*discordgo.InteractionResponse, error) {
+	if id, ok := customIDOf(i); ok {
+		prefix, state := ParseCustomID(id)
+
+		f.mu.Lock()
+		h, ok := f.Components[prefix]
+		f.mu.Unlock()
+
+		if !ok {
+			return nil, fmt.Errorf("unknown component `%s`", id)
+		}
+
+		return h(nil, i, state), nil
+	}
+
+	name := i.ApplicationCommandData().Name
+
+	f.mu.Lock()
+	cmd, ok := f.Commands[name]
+	f.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown command `%s`", name)
+	}
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		if cmd.Autocomplete == nil {
+			return nil, fmt.Errorf("command `%s` does not support autocompletion", name)
+		}
+		return cmd.Autocomplete(nil, i), nil
+	}
+
+	return cmd.Handler(nil, i), nil
+}
+
+func (f *Fake) GetAppID() string {
+	return f.AppID
+}
+
+func (f *Fake) GetChannelID(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id, ok := f.Channels[name]; ok {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("invalid channel name `%s`", name)
+}
+
+func (f *Fake) GetUserName(id string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if name, ok := f.Members[id]; ok {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("unknown member `%s`", id)
+}
+
+func (f *Fake) GetUserNames(ids []string) map[string]string {
+	names := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if name, err := f.GetUserName(id); err == nil {
+			names[id] = name
+		}
+	}
+
+	return names
+}
+
+func (f *Fake) GetRoleID(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id, ok := f.Roles[name]; ok {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("invalid role name `%s`", name)
+}
+
+func (f *Fake) GetMemberRoles(uID string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Members[uID]; !ok {
+		return nil, fmt.Errorf("unknown member `%s`", uID)
+	}
+
+	return slices.Clone(f.MemberRoles[uID]), nil
+}
+
+func (f *Fake) MemberRoleAdd(uID string, roleID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Members[uID]; !ok {
+		return fmt.Errorf("unknown member `%s`", uID)
+	}
+
+	if !slices.Contains(f.MemberRoles[uID], roleID) {
+		f.MemberRoles[uID] = append(f.MemberRoles[uID], roleID)
+	}
+	return nil
+}
+
+func (f *Fake) MemberRoleRemove(uID string, roleID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Members[uID]; !ok {
+		return fmt.Errorf("unknown member `%s`", uID)
+	}
+
+	f.MemberRoles[uID] = slices.DeleteFunc(f.MemberRoles[uID], func(id string) bool { return id == roleID })
+	return nil
+}
+
+func (f *Fake) MsgGet(chID string, msgID string) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.Messages[chID] {
+		if m.ID == msgID {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown message `%s` in channel `%s`", msgID, chID)
+}
+
+func (f *Fake) MsgList(chID string) ([]*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Discord returns at most 100 messages, newest first.
+	msgs := slices.Clone(f.Messages[chID])
+	slices.Reverse(msgs)
+	if len(msgs) > 100 {
+		msgs = msgs[:100]
+	}
+
+	return msgs, nil
+}
+
+func (f *Fake) MsgListAfter(chID string, afterID string) ([]*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var msgs []*discordgo.Message
+	for _, m := range f.Messages[chID] {
+		if CompareIDs(m.ID, afterID) > 0 {
+			msgs = append(msgs, m)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (f *Fake) MsgSend(chID string, content string) (*discordgo.Message, error) {
+	return f.MsgSendComplex(chID, &discordgo.MessageSend{Content: content})
+}
+
+func (f *Fake) MsgSendComplex(chID string, send *discordgo.MessageSend) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := &discordgo.Message{
+		ID:         f.nextID(),
+		ChannelID:  chID,
+		Author:     &discordgo.User{ID: f.AppID},
+		Content:    send.Content,
+		Embeds:     send.Embeds,
+		Components: send.Components,
+		Timestamp:  time.Now(),
+
+		MessageReference: send.Reference,
+	}
+
+	f.Messages[chID] = append(f.Messages[chID], m)
+	return m, nil
+}
+
+func (f *Fake) MsgEditComplex(edit *discordgo.MessageEdit) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.Messages[edit.Channel] {
+		if m.ID != edit.ID {
+			continue
+		}
+
+		if edit.Content != nil {
+			m.Content = *edit.Content
+		}
+		if edit.Embeds != nil {
+			m.Embeds = *edit.Embeds
+		}
+		if edit.Components != nil {
+			m.Components = *edit.Components
+		}
+
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("unknown message `%s` in channel `%s`", edit.ID, edit.Channel)
+}
+
+func (f *Fake) MsgReact(chID string, msgID string, reaction string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Reactions[msgID] = append(f.Reactions[msgID], reaction)
+	return nil
+}
+
+func (f *Fake) InteractionRespond(i *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Responses[i.ID] = append(f.Responses[i.ID], resp)
+	return nil
+}
+
+func (f *Fake) InteractionFollowup(i *discordgo.Interaction, params *discordgo.WebhookParams) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.Responses[i.ID]) == 0 {
+		return nil, fmt.Errorf("interaction `%s` has not been responded to", i.ID)
+	}
+
+	f.Followups[i.ID] = append(f.Followups[i.ID], params)
+	return &discordgo.Message{
+		ID:         f.nextID(),
+		ChannelID:  i.ChannelID,
+		Author:     &discordgo.User{ID: f.AppID},
+		Content:    params.Content,
+		Embeds:     params.Embeds,
+		Components: params.Components,
+		Flags:      params.Flags,
+		Timestamp:  time.Now(),
+	}, nil
+}
+
+func (f *Fake) CommandAdd(cmd Command) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Commands[cmd.Definition.Name]; ok {
+		return fmt.Errorf("command with name `%s` already exists", cmd.Definition.Name)
+	}
+
+	f.Commands[cmd.Definition.Name] = cmd
+	return nil
+}
+
+func (f *Fake) ComponentAdd(prefix string, h ComponentHandler) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.Contains(prefix, idSep) {
+		return fmt.Errorf("component prefix `%s` contains separator `%s`", prefix, idSep)
+	}
+	if _, ok := f.Components[prefix]; ok {
+		return fmt.Errorf("component with prefix `%s` already exists", prefix)
+	}
+
+	f.Components[prefix] = h
+	return nil
+}
+
+func (f *Fake) HandlerAdd(name string, handler any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Handlers[name]; ok {
+		return fmt.Errorf("handler for name `%s` already exists", name)
+	}
+
+	f.Handlers[name] = handler
+	return nil
+}
@@ -1,10 +1,15 @@
 package session
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
@@ -15,6 +20,13 @@ import (
 // https://discord.com/developers/docs/components/reference#component-reference
 const IS_COMPONENTS_V2 = 1 << 15
 
+// Duration for which resolved user names are cached (see
+// [Session.GetUserNames]).
+const nameTTL = 10 * time.Minute
+
+// Maximum number of members per request (see [Session.members]).
+const membersLimit = 1000
+
 // Session is a connection to a [*discordgo.Session] with additional metadata as
 // well as all registered event handlers (see [discordgo.EventHandler])
 // slash-commands (see [discordgo.ApplicationCommand]).
@@ -32,8 +44,27 @@ type Session struct {
 	// [discordgo.Session.AddHandler]).
 	Handlers map[string]func()
 
-	// Maps registered command names to their handler functions.
-	Commands map[string]Handler
+	// Maps registered command names to their commands.
+	Commands map[string]Command
+
+	// Maps registered custom ID prefixes to their component handlers.
+	Components map[string]ComponentHandler
+
+	// Caches resolved user names by user ID.
+	names   map[string]cachedName
+	namesMu sync.Mutex
+
+	// Held for reading by each executing event handler. Draining the session
+	// (see [Session.Drain]) acquires it for writing, waiting for all in-flight
+	// handlers before setting closing.
+	active  sync.RWMutex
+	closing bool
+}
+
+// cachedName is a resolved user name along with its expiry.
+type cachedName struct {
+	Name    string
+	Expires time.Time
 }
 
 // NewSession creates a new session, connecting the application to the given
@@ -44,7 +75,14 @@ func NewSession(token string, sID string) *Session {
 		log.Fatal("Failed to create session", "sID", sID, "err", err)
 	}
 
-	return &Session{dcs, "", sID, make(map[string]func()), make(map[string]Handler)}
+	return &Session{
+		dcs:        dcs,
+		ServerID:   sID,
+		Handlers:   make(map[string]func()),
+		Commands:   make(map[string]Command),
+		Components: make(map[string]ComponentHandler),
+		names:      make(map[string]cachedName),
+	}
 }
 
 // Open configures the underlying session.
@@ -53,13 +91,61 @@ func (s *Session) Open(cmds []Command) error {
 		return err
 	}
 
-	// Register generic handler for all slash-commands.
+	// Register generic handler for all slash-commands and their autocompletion.
 	s.HandlerAdd("handle-command", func(dcs *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := s.Commands[i.ApplicationCommandData().Name]; ok {
-			log.Info("Executing command", "name", i.ApplicationCommandData().Name)
-			if err := s.dcs.InteractionRespond(i.Interaction, h(dcs, i.Interaction)); err != nil {
-				log.Error("Execution failed", "name", i.ApplicationCommandData().Name, "err", err)
+		if i.Type != discordgo.InteractionApplicationCommand &&
+			i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+			return
+		}
+
+		name := i.ApplicationCommandData().Name
+		c, ok := s.Commands[name]
+		if !ok {
+			return
+		}
+
+		h := c.Handler
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			if c.Autocomplete == nil {
+				log.Warn("Autocompletion not supported", "name", name)
+				return
 			}
+			h = c.Autocomplete
+		}
+
+		log.Info("Executing command", "name", name, "type", i.Type)
+		resp := h(dcs, i.Interaction)
+		if resp == nil {
+			log.Debug("No response to command", "name", name, "type", i.Type)
+			return
+		}
+		if err := s.dcs.InteractionRespond(i.Interaction, resp); err != nil {
+			log.Error("Execution failed", "name", name, "type", i.Type, "err", err)
+		}
+	})
+
+	// Register generic handler for all message components and modals.
+	s.HandlerAdd("handle-component", func(dcs *discordgo.Session, i *discordgo.InteractionCreate) {
+		id, ok := customIDOf(i.Interaction)
+		if !ok {
+			return
+		}
+
+		prefix, state := ParseCustomID(id)
+		h, ok := s.Components[prefix]
+		if !ok {
+			log.Warn("Unknown component", "customID", id)
+			return
+		}
+
+		log.Info("Executing component", "prefix", prefix, "state", state, "type", i.Type)
+		resp := h(dcs, i.Interaction, state)
+		if resp == nil {
+			log.Debug("No response to component", "prefix", prefix, "state", state, "type", i.Type)
+			return
+		}
+		if err := s.dcs.InteractionRespond(i.Interaction, resp); err != nil {
+			log.Error("Execution failed", "prefix", prefix, "state", state, "type", i.Type, "err", err)
 		}
 	})
 
@@ -115,6 +201,12 @@ func (s *Session) awaitReady() error {
 	return nil
 }
 
+// GetAppID returns the application ID associated with the bot. The ID is only
+// available once the session has been opened (see [Session.Open]).
+func (s *Session) GetAppID() string {
+	return s.AppID
+}
+
 // GetUserName returns the server-local nickname for the user with the given ID.
 func (s *Session) GetUserName(id string) (string, error) {
 	member, err := s.dcs.GuildMember(s.ServerID, id)
@@ -123,18 +215,113 @@ func (s *Session) GetUserName(id string) (string, error) {
 		return "", err
 	}
 
+	return memberName(member), nil
+}
+
+// GetUserNames returns the server-local nicknames for all users with the given
+// IDs. Names are cached for a short amount of time, such that repeated lookups
+// do not result in additional requests. Once any name is missing from the
+// cache, the names of all server members are requested at once (see
+// [Session.members]). Users whose names cannot be resolved are omitted from
+// the result.
+func (s *Session) GetUserNames(ids []string) map[string]string {
+	names, complete := s.cachedNames(ids)
+	if complete {
+		return names
+	}
+
+	// The cache isn't locked while waiting for requests.
+	members, err := s.members()
+	if err != nil {
+		return names
+	}
+
+	s.namesMu.Lock()
+	defer s.namesMu.Unlock()
+
+	expires := time.Now().Add(nameTTL)
+	for _, m := range members {
+		if m.User != nil {
+			s.names[m.User.ID] = cachedName{memberName(m), expires}
+		}
+	}
+
+	for _, id := range ids {
+		c, ok := s.names[id]
+		if !ok {
+			// Cache users who left the server as well, such that they don't
+			// cause another request on each lookup.
+			c = cachedName{Expires: expires}
+			s.names[id] = c
+		}
+		if c.Name != "" {
+			names[id] = c.Name
+		}
+	}
+
+	return names
+}
+
+// cachedNames returns the cached names for all users with the given IDs, as
+// well as whether the cache contained all of them.
+func (s *Session) cachedNames(ids []string) (map[string]string, bool) {
+	s.namesMu.Lock()
+	defer s.namesMu.Unlock()
+
+	now := time.Now()
+	names := make(map[string]string, len(ids))
+	complete := true
+
+	for _, id := range ids {
+		c, ok := s.names[id]
+		if !ok || !now.Before(c.Expires) {
+			complete = false
+			continue
+		}
+		if c.Name != "" {
+			names[id] = c.Name
+		}
+	}
+
+	return names, complete
+}
+
+// members returns all members of the server, requested in pages of
+// [membersLimit]. Requires the server members intent.
+func (s *Session) members() ([]*discordgo.Member, error) {
+	var members []*discordgo.Member
+
+	var after string
+	for {
+		page, err := s.dcs.GuildMembers(s.ServerID, after, membersLimit)
+		if err != nil {
+			log.Warn("Failed to get members", "after", after, "err", err)
+			return nil, err
+		}
+		members = append(members, page...)
+
+		if len(page) < membersLimit || page[len(page)-1].User == nil {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// memberName returns the name to display for the given member, preferring the
+// server-local nickname over the global display name and username.
+func memberName(member *discordgo.Member) string {
 	if member.Nick != "" {
-		return member.Nick, nil
+		return member.Nick
 	}
 	if member.User != nil {
 		if member.User.GlobalName != "" {
-			return member.User.GlobalName, nil
+			return member.User.GlobalName
 		}
 		if member.User.Username != "" {
-			return member.User.Username, nil
+			return member.User.Username
 		}
 	}
-	return "Who dis?", nil
+	return "Who dis?"
 }
 
 // GetChannelID returns the ID for the channel with the given name.
@@ -154,6 +341,46 @@ func (s *Session) GetChannelID(name string) (string, error) {
 	return "", fmt.Errorf("invalid channel name `%s`", name)
 }
 
+// GetRoleID returns the ID for the role with the given name.
+func (s *Session) GetRoleID(name string) (string, error) {
+	roles, err := s.dcs.GuildRoles(s.ServerID)
+	if err != nil {
+		log.Warn("Failed to get role ID", "name", name, "err", err)
+		return "", err
+	}
+
+	for _, r := range roles {
+		if r.Name == name {
+			return r.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid role name `%s`", name)
+}
+
+// GetMemberRoles returns the IDs of all roles of the member with the given ID.
+func (s *Session) GetMemberRoles(uID string) ([]string, error) {
+	member, err := s.dcs.GuildMember(s.ServerID, uID)
+	if err != nil {
+		log.Warn("Failed to get member roles", "uID", uID, "err", err)
+		return nil, err
+	}
+
+	return member.Roles, nil
+}
+
+// MemberRoleAdd adds the role with the given ID to the member with the given
+// ID.
+func (s *Session) MemberRoleAdd(uID string, roleID string) error {
+	return s.dcs.GuildMemberRoleAdd(s.ServerID, uID, roleID)
+}
+
+// MemberRoleRemove removes the role with the given ID from the member with the
+// given ID.
+func (s *Session) MemberRoleRemove(uID string, roleID string) error {
+	return s.dcs.GuildMemberRoleRemove(s.ServerID, uID, roleID)
+}
+
 // MsgGet returns the message with the given ID from the given channel.
 func (s *Session) MsgGet(chID string, msgID string) (*discordgo.Message, error) {
 	return s.dcs.ChannelMessage(chID, msgID)
@@ -165,6 +392,50 @@ func (s *Session) MsgList(chID string) ([]*discordgo.Message, error) {
 	return s.dcs.ChannelMessages(chID, 100, "", "", "")
 }
 
+// MsgListAfter returns all messages from the channel with the given ID that
+// were posted after the message with ID afterID, oldest first. Messages are
+// fetched in pages of 100.
+func (s *Session) MsgListAfter(chID string, afterID string) ([]*discordgo.Message, error) {
+	var msgs []*discordgo.Message
+	for {
+		page, err := s.dcs.ChannelMessages(chID, 100, "", afterID, "")
+		if err != nil {
+			log.Warn("Failed to retrieve messages", "chID", chID, "afterID", afterID, "err", err)
+			return nil, err
+		}
+
+		// Pages are not guaranteed to be ordered oldest first.
+		slices.SortFunc(page, func(a, b *discordgo.Message) int { return CompareIDs(a.ID, b.ID) })
+		msgs = append(msgs, page...)
+
+		if len(page) < 100 {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	log.Debug("Messages retrieved", "chID", chID, "count", len(msgs))
+	return msgs, nil
+}
+
+// CompareIDs compares two snowflake IDs by their creation time, returning -1,
+// 0 or +1 (see [cmp.Compare]). Invalid IDs are ordered before valid ones.
+func CompareIDs(a string, b string) int {
+	ai, aErr := strconv.ParseUint(a, 10, 64)
+	bi, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr != nil && bErr != nil:
+		return 0
+	case aErr != nil:
+		return -1
+	case bErr != nil:
+		return 1
+	}
+
+	return cmp.Compare(ai, bi)
+}
+
 // MsgSend sends a message with contents content to the channel with ID chID.
 func (s *Session) MsgSend(chID string, content string) (*discordgo.Message, error) {
 	m, err := s.dcs.ChannelMessageSend(chID, content)
@@ -206,6 +477,30 @@ func (s *Session) MsgReact(chID string, msgID string, reaction string) error {
 	return s.dcs.MessageReactionAdd(chID, msgID, reaction)
 }
 
+// InteractionRespond responds to the given interaction. Only required by
+// handlers responding on their own (see [Handler]).
+func (s *Session) InteractionRespond(i *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	if err := s.dcs.InteractionRespond(i, resp); err != nil {
+		log.Warn("Failed to respond to interaction", "id", i.ID, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// InteractionFollowup sends a follow-up message for the given interaction,
+// e.g. to complete a deferred response.
+func (s *Session) InteractionFollowup(i *discordgo.Interaction, params *discordgo.WebhookParams) (*discordgo.Message, error) {
+	m, err := s.dcs.FollowupMessageCreate(i, true, params)
+	if err != nil {
+		log.Warn("Failed to send follow-up", "id", i.ID, "err", err)
+		return nil, err
+	}
+
+	log.Info("Follow-up sent", "id", i.ID, "msg", params)
+	return m, nil
+}
+
 // CommandAdd adds a new slash-command (see [discordgo.ApplicationCommand]) from
 // a [Command].
 func (s *Session) CommandAdd(cmd Command) error {
@@ -218,7 +513,23 @@ func (s *Session) CommandAdd(cmd Command) error {
 	}
 
 	log.Info("Command registered", "name", cmd.Definition.Name)
-	s.Commands[cmd.Definition.Name] = cmd.Handler
+	s.Commands[cmd.Definition.Name] = cmd
+	return nil
+}
+
+// ComponentAdd registers a handler for all message components and modals with
+// custom IDs starting with the given prefix (see [CustomID]). Errors if a
+// handler for the given prefix already exists.
+func (s *Session) ComponentAdd(prefix string, h ComponentHandler) error {
+	if strings.Contains(prefix, idSep) {
+		return fmt.Errorf("component prefix `%s` contains separator `%s`", prefix, idSep)
+	}
+	if _, ok := s.Components[prefix]; ok {
+		return fmt.Errorf("component with prefix `%s` already exists", prefix)
+	}
+
+	log.Info("Component registered", "prefix", prefix)
+	s.Components[prefix] = h
 	return nil
 }
 
@@ -233,8 +544,16 @@ func (s *Session) HandlerAdd(name string, handler any) error {
 	rv := reflect.ValueOf(handler)
 	rt := rv.Type()
 
-	// Wrap handler to allow generic logging for all handlers.
+	// Wrap handler to allow generic logging and draining for all handlers.
 	fn := reflect.MakeFunc(rt, func(in []reflect.Value) []reflect.Value {
+		s.active.RLock()
+		defer s.active.RUnlock()
+
+		if s.closing {
+			log.Debug("Dropping event during shutdown", "name", name)
+			return nil
+		}
+
 		log.Info("Executing handler", "name", name)
 		rv.Call(in)
 		return nil
@@ -251,5 +570,40 @@ func (s *Session) HandlerRemove(name string) {
 	if h, ok := s.Handlers[name]; ok {
 		log.Debug("Handler removed", "name", name)
 		h()
+		delete(s.Handlers, name)
+	}
+}
+
+// Drain stops the session from accepting new events and waits for all
+// in-flight event handlers to return, or until ctx is done. Events received
+// afterwards are dropped.
+func (s *Session) Drain(ctx context.Context) error {
+	log.Info("Draining session")
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Lock()
+		s.closing = true
+		s.active.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Debug("Session drained")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
+
+// Close removes all registered event handlers and closes the underlying
+// session. Sessions should be drained before closing (see [Session.Drain]).
+func (s *Session) Close() error {
+	log.Info("Closing session")
+	for name := range s.Handlers {
+		s.HandlerRemove(name)
+	}
+
+	return s.dcs.Close()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+	"tons-of-stats/db"
+
+	"github.com/charmbracelet/log"
+)
+
+// breakStreaks resets the current streak of all users who did not play on the
+// given, completed puzzle day (see [models.TotalStats.Streak]).
+func breakStreaks(ctx context.Context, day time.Time) error {
+	date := day.Format(time.DateOnly)
+	log.Info("Breaking streaks", "day", date)
+
+	return dal.DB.Transaction(ctx, func(tx db.Tx) error {
+		txTotal := dal.Total.WithTx(tx)
+
+		totals, err := txTotal.Find(db.Where("streak", ">", 0), db.Where("last_played", "<", date))
+		if err != nil {
+			return err
+		}
+
+		for _, t := range totals {
+			t.Streak = 0
+			if err := txTotal.Update(t.UserID, t); err != nil {
+				return err
+			}
+		}
+
+		log.Info("Streaks broken", "day", date, "users", len(totals))
+		return nil
+	})
+}
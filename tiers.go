@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// announceTier posts a promotion or demotion message in the stats channel if
+// the change in the user's Elo moved them to another tier (see [Env.Tiers]).
+func announceTier(uID string, before int, after int) {
+	from, to := env.Tiers.Index(before), env.Tiers.Index(after)
+	if from == to {
+		return
+	}
+
+	chID, err := session.GetChannelID(env.StatsCh)
+	if err != nil {
+		log.Warn("Failed to announce tier change", "uID", uID, "err", err)
+		return
+	}
+
+	content := fmt.Sprintf("⬆️  <@%s> has been promoted to **%s**!", uID, env.Tiers[to].Name)
+	if to < from {
+		content = fmt.Sprintf("⬇️  <@%s> has been demoted to **%s**.", uID, env.Tiers[to].Name)
+	}
+
+	// Announcements mention users without notifying them.
+	_, err = session.MsgSendComplex(chID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Warn("Failed to announce tier change", "uID", uID, "err", err)
+	}
+}